@@ -0,0 +1,87 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Arturomtz8/github-inspector/pkg/bluesky"
+	"github.com/Arturomtz8/github-inspector/pkg/pipeline"
+)
+
+var blueskyPostLanguage string
+
+// blueskyCmd represents the bluesky command
+var blueskyCmd = &cobra.Command{
+	Use:   "bluesky",
+	Short: "Publish Go Repos to a Bluesky account",
+	Long: `Publish the trending Go Repos as posts of a Bluesky account.
+The account is given by BLUESKY_HANDLE and BLUESKY_APP_PASSWORD, an app password created in its settings.
+BLUESKY_PDS_HOST is the PDS of the account, https://bsky.social by default.`,
+	Run: func(_ *cobra.Command, _ []string) {
+		opts, err := blueskyOptions(language, channel)
+		if err != nil {
+			log.Fatal(err)
+		}
+		opts.DryRun = dryRun
+
+		ctx := context.Background()
+		if err := bluesky.PublishRepos(ctx, newBlueskyClient(), os.Getenv("REDIS_URI"), opts); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+// newBlueskyClient returns the client of the account given by the environment.
+func newBlueskyClient() *bluesky.Client {
+	return bluesky.NewClient(os.Getenv("BLUESKY_PDS_HOST"), os.Getenv("BLUESKY_HANDLE"), os.Getenv("BLUESKY_APP_PASSWORD"))
+}
+
+// blueskyOptions returns the options given by the flags, for the repos of the language.
+func blueskyOptions(language, channel string) (bluesky.Options, error) {
+	tmpls, err := loadTemplates("bluesky")
+	if err != nil {
+		return bluesky.Options{}, err
+	}
+
+	policy, err := loadFilter()
+	if err != nil {
+		return bluesky.Options{}, err
+	}
+
+	overrides, err := loadCuration(curationFile)
+	if err != nil {
+		return bluesky.Options{}, err
+	}
+
+	return bluesky.Options{
+		TrendingOptions: pipeline.TrendingOptions{
+			Language:  language,
+			Channel:   channel,
+			SeenStore: os.Getenv("SEEN_STORE"),
+			Templates: tmpls,
+			Filter:    policy,
+			Curation:  overrides,
+		},
+		PostLanguage: blueskyPostLanguage,
+	}, nil
+}
+
+func init() {
+	rootCmd.AddCommand(blueskyCmd)
+
+	blueskyCmd.Flags().StringVar(&language, "language", "Go", "language of the trending repos")
+	blueskyCmd.Flags().StringVar(&channel, "channel", "default", "name of the channel, keeps apart the repos seen by several bots")
+	blueskyCmd.Flags().StringVar(&blueskyPostLanguage, "post-language", "en", "ISO 639 code of the language of the posts")
+	blueskyCmd.Flags().StringVar(&templateOpts.Dir, "template-dir", "", "directory of templates overriding the embedded ones, bluesky/repo.tmpl only applies to Bluesky (default ~/.config/github-inspector/templates)")
+	blueskyCmd.Flags().StringVar(&templateOpts.Languages, "languages", "", "JSON file mapping languages to their hashtags, emoji and template variant")
+	blueskyCmd.Flags().StringVar(&filterFile, "filter", "", "JSON file with the rules the repos have to pass to be published")
+	blueskyCmd.Flags().StringVar(&where, "where", "", `expression the repos have to satisfy, e.g. 'stars > 300 && any(topics, # == "cli")'`)
+	blueskyCmd.Flags().StringVar(&curationFile, "curation", "", "JSON file pinning repos and overriding their descriptions and comments")
+	blueskyCmd.Flags().BoolVar(&dryRun, "dry-run", false, "print the post records as JSON instead of creating them, without writing to the seen store")
+}
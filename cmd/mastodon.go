@@ -0,0 +1,103 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Arturomtz8/github-inspector/pkg/mastodon"
+	"github.com/Arturomtz8/github-inspector/pkg/pipeline"
+)
+
+var (
+	mastodonVisibility     string
+	mastodonStatusLanguage string
+	mastodonContentWarning string
+	mastodonMaxChars       int
+)
+
+// mastodonCmd represents the mastodon command
+var mastodonCmd = &cobra.Command{
+	Use:   "mastodon",
+	Short: "Publish Go Repos to a Mastodon account",
+	Long: `Publish the trending Go Repos as statuses of a Mastodon account.
+The instance and the account are given by MASTODON_INSTANCE_URL and MASTODON_ACCESS_TOKEN,
+the token needs the write:statuses scope.`,
+	Run: func(_ *cobra.Command, _ []string) {
+		opts, err := mastodonOptions(language, channel)
+		if err != nil {
+			log.Fatal(err)
+		}
+		opts.DryRun = dryRun
+
+		ctx := context.Background()
+		if err := mastodon.PublishRepos(ctx, newMastodonClient(), os.Getenv("REDIS_URI"), opts); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+// newMastodonClient returns the client of the account given by the environment.
+func newMastodonClient() *mastodon.Client {
+	return mastodon.NewClient(os.Getenv("MASTODON_INSTANCE_URL"), os.Getenv("MASTODON_ACCESS_TOKEN"))
+}
+
+// mastodonOptions returns the options given by the flags, for the repos of the language.
+func mastodonOptions(language, channel string) (mastodon.Options, error) {
+	visibility, err := mastodon.ParseVisibility(mastodonVisibility)
+	if err != nil {
+		return mastodon.Options{}, err
+	}
+
+	tmpls, err := loadTemplates("mastodon")
+	if err != nil {
+		return mastodon.Options{}, err
+	}
+
+	policy, err := loadFilter()
+	if err != nil {
+		return mastodon.Options{}, err
+	}
+
+	overrides, err := loadCuration(curationFile)
+	if err != nil {
+		return mastodon.Options{}, err
+	}
+
+	return mastodon.Options{
+		TrendingOptions: pipeline.TrendingOptions{
+			Language:  language,
+			Channel:   channel,
+			SeenStore: os.Getenv("SEEN_STORE"),
+			Templates: tmpls,
+			Filter:    policy,
+			Curation:  overrides,
+		},
+		Visibility:     visibility,
+		StatusLanguage: mastodonStatusLanguage,
+		ContentWarning: mastodonContentWarning,
+		MaxChars:       mastodonMaxChars,
+	}, nil
+}
+
+func init() {
+	rootCmd.AddCommand(mastodonCmd)
+
+	mastodonCmd.Flags().StringVar(&language, "language", "Go", "language of the trending repos")
+	mastodonCmd.Flags().StringVar(&channel, "channel", "default", "name of the channel, keeps apart the repos seen by several bots")
+	mastodonCmd.Flags().StringVar(&mastodonVisibility, "visibility", "public", "visibility of the statuses: public, unlisted, private or direct")
+	mastodonCmd.Flags().StringVar(&mastodonStatusLanguage, "status-language", "en", "ISO 639 code of the language of the statuses")
+	mastodonCmd.Flags().StringVar(&mastodonContentWarning, "content-warning", "", "content warning shown instead of the statuses until they're expanded")
+	mastodonCmd.Flags().IntVar(&mastodonMaxChars, "max-chars", mastodon.DefaultMaxChars, "length limit of the statuses on the instance")
+	mastodonCmd.Flags().StringVar(&templateOpts.Dir, "template-dir", "", "directory of templates overriding the embedded ones, mastodon/repo.tmpl only applies to Mastodon (default ~/.config/github-inspector/templates)")
+	mastodonCmd.Flags().StringVar(&templateOpts.Languages, "languages", "", "JSON file mapping languages to their hashtags, emoji and template variant")
+	mastodonCmd.Flags().StringVar(&filterFile, "filter", "", "JSON file with the rules the repos have to pass to be published")
+	mastodonCmd.Flags().StringVar(&where, "where", "", `expression the repos have to satisfy, e.g. 'stars > 300 && any(topics, # == "cli")'`)
+	mastodonCmd.Flags().StringVar(&curationFile, "curation", "", "JSON file pinning repos and overriding their descriptions and comments")
+	mastodonCmd.Flags().BoolVar(&dryRun, "dry-run", false, "print the statuses as they would appear instead of posting them, without writing to the seen store")
+}
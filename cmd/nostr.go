@@ -5,12 +5,47 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 
+	"github.com/Arturomtz8/github-inspector/pkg/curation"
+	"github.com/Arturomtz8/github-inspector/pkg/filter"
+	"github.com/Arturomtz8/github-inspector/pkg/github"
+	"github.com/Arturomtz8/github-inspector/pkg/lock"
 	"github.com/Arturomtz8/github-inspector/pkg/nostr"
+	"github.com/Arturomtz8/github-inspector/pkg/pipeline"
+	"github.com/Arturomtz8/github-inspector/pkg/templates"
+)
+
+var (
+	republish      = pipeline.DefaultRepublishPolicy()
+	runLock        = nostr.DefaultRunLockOptions()
+	language       string
+	channel        string
+	threaded       bool
+	quorum         int
+	flushAll       bool
+	deleteRepo     string
+	deleteEventID  string
+	deleteReason   string
+	digestLanguage string
+	digestSince    string
+	templateOpts   templates.Options
+	filterFile     string
+	curationFile   string
+	where          string
+	dryRun         bool
+	preview        bool
+	review         bool
+	paceWindow     string
+	paceTimeZone   string
+	paceJitter     bool
 )
 
 // nostrCmd represents the nostr command
@@ -21,16 +56,349 @@ var nostrCmd = &cobra.Command{
 	Run: func(_ *cobra.Command, _ []string) {
 		sk := os.Getenv("NOSTR_HEX_SK")
 		redisURI := os.Getenv("REDIS_URI")
+		seenURI := os.Getenv("SEEN_STORE")
+
+		tmpls, err := loadTemplates("nostr")
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		policy, err := loadFilter()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		overrides, err := loadCuration(curationFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		pacing, err := loadPacing()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		ctx := context.Background()
+		if err := nostr.PusblishRepos(ctx, sk, redisURI, nostr.PublishOptions{
+			TrendingOptions: pipeline.TrendingOptions{
+				Language:  language,
+				Channel:   channel,
+				SeenStore: seenURI,
+				Republish: &republish,
+				Templates: tmpls,
+				Filter:    policy,
+				Curation:  overrides,
+				DryRun:    dryRun,
+			},
+			Threaded: threaded,
+			Quorum:   quorum,
+			Preview:  preview,
+			Review:   review,
+			Lock:     &runLock,
+			Pacing:   pacing,
+		}); errors.Is(err, lock.ErrHeld) {
+			// Not a failure, the other run publishes the repos.
+			log.Printf("%v, exiting", err)
+		} else if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+// resumeCmd represents the nostr resume command
+var resumeCmd = &cobra.Command{
+	Use:   "resume",
+	Short: "Publish the paced notes left by a stopped run",
+	Long: `Publish the notes a run with --pace-window queued and didn't publish,
+e.g. because it was stopped, each one at the time it was due.`,
+	Run: func(_ *cobra.Command, _ []string) {
+		sk := os.Getenv("NOSTR_HEX_SK")
+		redisURI := os.Getenv("REDIS_URI")
+		seenURI := os.Getenv("SEEN_STORE")
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		if err := nostr.ResumePaced(ctx, sk, redisURI, seenURI, channel, quorum, runLock); errors.Is(err, lock.ErrHeld) {
+			log.Printf("%v, exiting", err)
+		} else if err != nil && !errors.Is(err, context.Canceled) {
+			log.Fatal(err)
+		}
+	},
+}
+
+// digestCmd represents the nostr digest command
+var digestCmd = &cobra.Command{
+	Use:   "digest",
+	Short: "Publish the daily digest of Go Repos to Nostr Relays",
+	Long: `Publish a long-form article (NIP-23) with the trending Go Repos of the day.
+Running it again the same day replaces the previous article.`,
+	Run: func(_ *cobra.Command, _ []string) {
+		sk := os.Getenv("NOSTR_HEX_SK")
+
+		repos, err := github.GetTrendingRepos(digestSince, digestLanguage)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		overrides, err := loadCuration(curationFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		repos.Items = overrides.Merge(repos.Items, pipeline.FetchRepo)
+
+		tmpls, err := loadTemplates("nostr")
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		opts := nostr.DefaultDigestOptions()
+		opts.Language = digestLanguage
+		opts.Since = digestSince
+		opts.Hashtags = tmpls.Languages().Hashtags(digestLanguage)
 
 		ctx := context.Background()
-		if err := nostr.PusblishRepos(ctx, sk, redisURI); err != nil {
+		if err := nostr.PublishDigest(ctx, sk, repos, opts); err != nil {
 			log.Fatal(err)
 		}
 	},
 }
 
+// listenCmd represents the nostr listen command
+var listenCmd = &cobra.Command{
+	Use:   "listen",
+	Short: "Answer Nostr mentions with trending repos",
+	Long: `Listen to the notes that mention the bot and reply to requests like
+"trending rust weekly" with the trending repos of that language.`,
+	Run: func(_ *cobra.Command, _ []string) {
+		sk := os.Getenv("NOSTR_HEX_SK")
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		if err := nostr.Listen(ctx, sk); err != nil && !errors.Is(err, context.Canceled) {
+			log.Fatal(err)
+		}
+	},
+}
+
+// subscriptionsCmd represents the nostr subscriptions command
+var subscriptionsCmd = &cobra.Command{
+	Use:   "subscriptions",
+	Short: "Manage the direct message subscriptions",
+	Long: `Users can send the bot an encrypted direct message with
+"subscribe go daily", "unsubscribe go" or "list"
+to receive the trending repos privately.`,
+}
+
+// subscriptionsListenCmd represents the nostr subscriptions listen command
+var subscriptionsListenCmd = &cobra.Command{
+	Use:   "listen",
+	Short: "Handle the subscription commands sent as direct messages",
+	Run: func(_ *cobra.Command, _ []string) {
+		sk := os.Getenv("NOSTR_HEX_SK")
+		redisURI := os.Getenv("REDIS_URI")
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		tmpls, err := loadTemplates("nostr")
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if err := nostr.ListenDMs(ctx, sk, redisURI, tmpls.Languages()); err != nil && !errors.Is(err, context.Canceled) {
+			log.Fatal(err)
+		}
+	},
+}
+
+// subscriptionsSendCmd represents the nostr subscriptions send command
+var subscriptionsSendCmd = &cobra.Command{
+	Use:   "send",
+	Short: "Send every subscriber the trending repos they subscribed to",
+	Long: `Send every subscriber a direct message with the trending repos of their languages.
+It's meant to run once a day.`,
+	Run: func(_ *cobra.Command, _ []string) {
+		sk := os.Getenv("NOSTR_HEX_SK")
+		redisURI := os.Getenv("REDIS_URI")
+
+		tmpls, err := loadTemplates("nostr")
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		ctx := context.Background()
+		if err := nostr.SendSubscriptions(ctx, sk, redisURI, tmpls.Languages()); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+// deleteCmd represents the nostr delete command
+var deleteCmd = &cobra.Command{
+	Use:   "delete",
+	Short: "Retract published notes",
+	Long: `Send a NIP-09 deletion event for the notes published for a repo,
+or for a single event, to every relay that accepted them.
+The relays that don't accept the deletion are kept, running the command again retries them.`,
+	Run: func(_ *cobra.Command, _ []string) {
+		sk := os.Getenv("NOSTR_HEX_SK")
+		redisURI := os.Getenv("REDIS_URI")
+
+		ctx := context.Background()
+
+		switch {
+		case deleteRepo != "":
+			if err := nostr.DeleteRepo(ctx, sk, redisURI, channel, language, deleteRepo, deleteReason); err != nil {
+				log.Fatal(err)
+			}
+		case deleteEventID != "":
+			if err := nostr.DeleteEvent(ctx, sk, redisURI, deleteEventID, deleteReason); err != nil {
+				log.Fatal(err)
+			}
+		default:
+			log.Fatal("either --repo or --event must be provided")
+		}
+	},
+}
+
+// flushCmd represents the nostr flush command
+var flushCmd = &cobra.Command{
+	Use:   "flush",
+	Short: "Redeliver the events pending in the outbox",
+	Long: `Redeliver the events that some relays didn't acknowledge.
+Events are retried with an exponential backoff, use --all to ignore it.`,
+	Run: func(_ *cobra.Command, _ []string) {
+		redisURI := os.Getenv("REDIS_URI")
+		seenURI := os.Getenv("SEEN_STORE")
+
+		ctx := context.Background()
+		if err := nostr.FlushOutbox(ctx, redisURI, seenURI, flushAll); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+// loadTemplates loads the templates of the sink given by --template, --template-dir and --languages.
+func loadTemplates(sink string) (*templates.Set, error) {
+	opts := templateOpts
+	opts.Sink = sink
+	if opts.Dir == "" {
+		opts.Dir = templates.DefaultDir()
+	}
+
+	return templates.Load(opts)
+}
+
+// loadFilter loads the filter given by --filter and --where, nil if there's none.
+func loadFilter() (*filter.Policy, error) {
+	var policy *filter.Policy
+	if filterFile != "" {
+		var err error
+		if policy, err = filter.Load(filterFile); err != nil {
+			return nil, err
+		}
+	}
+
+	if where != "" {
+		if policy == nil {
+			policy = &filter.Policy{}
+		}
+
+		if err := policy.And(where); err != nil {
+			return nil, err
+		}
+	}
+
+	return policy, nil
+}
+
+// loadCuration loads the curation file, nil if there's none.
+func loadCuration(path string) (*curation.Curation, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	return curation.Load(path)
+}
+
+// loadPacing returns the pacing given by --pace-window and --pace-timezone, nil if there's none.
+func loadPacing() (*nostr.PacingOptions, error) {
+	if paceWindow == "" {
+		return nil, nil
+	}
+
+	pacing, err := nostr.ParsePacingWindow(paceWindow)
+	if err != nil {
+		return nil, err
+	}
+
+	if pacing.Location, err = time.LoadLocation(paceTimeZone); err != nil {
+		return nil, err
+	}
+	pacing.Jitter = paceJitter
+
+	return &pacing, nil
+}
+
 func init() {
 	rootCmd.AddCommand(nostrCmd)
+	nostrCmd.AddCommand(digestCmd)
+	nostrCmd.AddCommand(listenCmd)
+	nostrCmd.AddCommand(subscriptionsCmd)
+	nostrCmd.AddCommand(deleteCmd)
+	nostrCmd.AddCommand(flushCmd)
+	nostrCmd.AddCommand(resumeCmd)
+	subscriptionsCmd.AddCommand(subscriptionsListenCmd)
+	subscriptionsCmd.AddCommand(subscriptionsSendCmd)
+	subscriptionsCmd.PersistentFlags().StringVar(&templateOpts.Dir, "template-dir", "", "directory whose languages.json lists the languages that can be subscribed to (default ~/.config/github-inspector/templates)")
+	subscriptionsCmd.PersistentFlags().StringVar(&templateOpts.Languages, "languages", "", "JSON file listing the languages that can be subscribed to")
+
+	deleteCmd.Flags().StringVar(&deleteRepo, "repo", "", "full name of the repo to retract, e.g. owner/name")
+	deleteCmd.Flags().StringVar(&deleteEventID, "event", "", "ID of the event to retract")
+	deleteCmd.Flags().StringVar(&deleteReason, "reason", "", "reason of the deletion")
+	deleteCmd.Flags().StringVar(&channel, "channel", "default", "name of the channel the repo was published to")
+	deleteCmd.Flags().StringVar(&language, "language", "Go", "language of the trending repos the repo was published with")
+	deleteCmd.MarkFlagsMutuallyExclusive("repo", "event")
+
+	nostrCmd.Flags().StringVar(&language, "language", "Go", "language of the trending repos")
+	nostrCmd.Flags().StringVar(&channel, "channel", "default", "name of the channel, keeps apart the repos seen by several bots")
+	nostrCmd.Flags().BoolVar(&threaded, "threaded", false, "publish a root note and every repo as a reply to it")
+	nostrCmd.Flags().DurationVar(&republish.MinInterval, "republish-after", republish.MinInterval, "minimum time before a repo can be published again")
+	nostrCmd.Flags().IntVar(&republish.MinStarGain, "republish-stars", republish.MinStarGain, "new stars that make a repo eligible again, 0 disables it")
+	nostrCmd.Flags().Float64Var(&republish.MinStarGainPercent, "republish-percent", republish.MinStarGainPercent, "percentage of new stars that make a repo eligible again, 0 disables it")
+	nostrCmd.Flags().IntSliceVar(&republish.Milestones, "milestones", republish.Milestones, "star counts worth publishing a repo again")
+	nostrCmd.Flags().StringVar(&templateOpts.Repo, "template", "", "file overriding the template of the repo notes")
+	nostrCmd.Flags().StringVar(&templateOpts.Dir, "template-dir", "", "directory of templates overriding the embedded ones (default ~/.config/github-inspector/templates)")
+	nostrCmd.Flags().StringVar(&templateOpts.Languages, "languages", "", "JSON file mapping languages to their hashtags, emoji and template variant")
+	nostrCmd.Flags().StringVar(&filterFile, "filter", "", "JSON file with the rules the repos have to pass to be published")
+	nostrCmd.Flags().StringVar(&where, "where", "", `expression the repos have to satisfy, e.g. 'stars > 300 && any(topics, # == "cli")'`)
+	nostrCmd.Flags().StringVar(&curationFile, "curation", "", "JSON file pinning repos and overriding their descriptions and comments")
+	nostrCmd.Flags().BoolVar(&dryRun, "dry-run", false, "print the signed events as JSON instead of publishing them, without writing to the seen store")
+	nostrCmd.Flags().BoolVar(&preview, "preview", false, "print the notes as they would appear instead of publishing them, without writing to the seen store")
+	nostrCmd.Flags().BoolVar(&review, "review", false, "queue the notes for review instead of publishing them, see the review command")
+	nostrCmd.Flags().BoolVar(&runLock.Disabled, "no-lock", false, "don't take the lock of the channel, letting concurrent runs publish")
+	nostrCmd.Flags().DurationVar(&runLock.Lease, "lock-lease", runLock.Lease, "how long the lock of the channel outlives a run that died")
+	nostrCmd.Flags().DurationVar(&runLock.Wait, "lock-wait", 0, "how long to wait for the lock of the channel held by another run, 0 exits right away")
+	nostrCmd.Flags().IntVar(&quorum, "quorum", 0, "number of relays that have to acknowledge a note (default 2)")
+	nostrCmd.Flags().StringVar(&paceWindow, "pace-window", "", "time window the notes are spread over instead of published at once, e.g. 16:00-22:00")
+	nostrCmd.Flags().StringVar(&paceTimeZone, "pace-timezone", "UTC", "IANA time zone of the pace window, e.g. America/Mexico_City")
+	nostrCmd.Flags().BoolVar(&paceJitter, "pace-jitter", false, "publish the paced notes at random times instead of evenly spaced")
+
+	resumeCmd.Flags().StringVar(&channel, "channel", "default", "name of the channel of the paced notes")
+	resumeCmd.Flags().IntVar(&quorum, "quorum", 0, "number of relays that have to acknowledge a note (default 2)")
+	resumeCmd.Flags().BoolVar(&runLock.Disabled, "no-lock", false, "don't take the lock of the channel, letting concurrent runs publish")
+	resumeCmd.Flags().DurationVar(&runLock.Lease, "lock-lease", runLock.Lease, "how long the lock of the channel outlives a run that died")
+	resumeCmd.Flags().DurationVar(&runLock.Wait, "lock-wait", 0, "how long to wait for the lock of the channel held by another run, 0 exits right away")
+
+	flushCmd.Flags().BoolVar(&flushAll, "all", false, "redeliver every pending event, ignoring the backoff")
+
+	digestCmd.Flags().StringVar(&digestLanguage, "language", "Go", "language of the trending repos")
+	digestCmd.Flags().StringVar(&digestSince, "since", github.TimeToday, "trending period: daily, weekly or monthly")
+	digestCmd.Flags().StringVar(&curationFile, "curation", "", "JSON file pinning repos and overriding their descriptions and comments")
+	digestCmd.Flags().StringVar(&templateOpts.Dir, "template-dir", "", "directory whose languages.json gives the hashtags of the article (default ~/.config/github-inspector/templates)")
+	digestCmd.Flags().StringVar(&templateOpts.Languages, "languages", "", "JSON file mapping languages to their hashtags")
 
 	// Here you will define your flags and configuration settings.
 
@@ -0,0 +1,212 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Arturomtz8/github-inspector/pkg/lock"
+	"github.com/Arturomtz8/github-inspector/pkg/nostr"
+)
+
+var (
+	reviewContent  string
+	reviewBlock    bool
+	approvedQuorum int
+)
+
+// reviewCmd represents the review command
+var reviewCmd = &cobra.Command{
+	Use:   "review",
+	Short: "List the notes waiting for review",
+	Long: `List the notes queued by "github-inspector nostr --review".
+Every note can be approved, edited or rejected, and the approved ones
+are sent by "github-inspector publish-approved".`,
+	Run: func(_ *cobra.Command, _ []string) {
+		items, err := nostr.ListReview(context.Background(), os.Getenv("REDIS_URI"), channel)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if len(items) == 0 {
+			log.Print("no notes waiting for review")
+			return
+		}
+
+		for _, item := range items {
+			fmt.Printf("── #%d %s (%s, queued %s) ──\n%s\n\n",
+				item.ID, item.Repo, item.Status, item.QueuedAt.Format("2006-01-02 15:04"), item.Content)
+		}
+	},
+}
+
+// reviewApproveCmd represents the review approve command
+var reviewApproveCmd = &cobra.Command{
+	Use:   "approve <id>...",
+	Short: "Approve notes, so the next publish-approved run sends them",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(_ *cobra.Command, args []string) {
+		for _, id := range parseReviewIDs(args) {
+			if err := nostr.ApproveReview(context.Background(), os.Getenv("REDIS_URI"), channel, id, reviewContent); err != nil {
+				log.Fatalf("approving note %d: %v", id, err)
+			}
+
+			log.Printf("note %d approved", id)
+		}
+	},
+}
+
+// reviewEditCmd represents the review edit command
+var reviewEditCmd = &cobra.Command{
+	Use:   "edit <id>",
+	Short: "Edit the content of a note",
+	Long: `Replace the content of a note with --content,
+or edit it in $EDITOR if --content is not given.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(_ *cobra.Command, args []string) {
+		ctx := context.Background()
+		redisURI := os.Getenv("REDIS_URI")
+		id := parseReviewIDs(args)[0]
+
+		content := reviewContent
+		if content == "" {
+			item, err := nostr.GetReview(ctx, redisURI, channel, id)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			if content, err = editInEditor(item.Content); err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		if err := nostr.EditReview(ctx, redisURI, channel, id, content); err != nil {
+			log.Fatal(err)
+		}
+
+		log.Printf("note %d edited", id)
+	},
+}
+
+// reviewRejectCmd represents the review reject command
+var reviewRejectCmd = &cobra.Command{
+	Use:   "reject <id>...",
+	Short: "Reject notes",
+	Long: `Drop notes from the review queue. Their repos are recorded as published,
+so they are only proposed again when the republish policy allows it,
+or never with --block.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(_ *cobra.Command, args []string) {
+		for _, id := range parseReviewIDs(args) {
+			err := nostr.RejectReview(context.Background(), os.Getenv("REDIS_URI"), os.Getenv("SEEN_STORE"), channel, id, reviewBlock)
+			if err != nil {
+				log.Fatalf("rejecting note %d: %v", id, err)
+			}
+
+			log.Printf("note %d rejected", id)
+		}
+	},
+}
+
+// publishApprovedCmd represents the publish-approved command
+var publishApprovedCmd = &cobra.Command{
+	Use:   "publish-approved",
+	Short: "Publish the approved notes of the review queue to Nostr Relays",
+	Long: `Publish the approved notes of the review queue of the channel to Nostr Relays.
+The notes stay in the queue until the outbox has them, and the notes of the repos
+published since they were queued are dropped. The lock of the channel is held meanwhile.`,
+	Run: func(_ *cobra.Command, _ []string) {
+		sk := os.Getenv("NOSTR_HEX_SK")
+		redisURI := os.Getenv("REDIS_URI")
+		seenURI := os.Getenv("SEEN_STORE")
+
+		if err := nostr.PublishApproved(context.Background(), sk, redisURI, seenURI, channel, approvedQuorum, runLock); errors.Is(err, lock.ErrHeld) {
+			log.Printf("%v, exiting", err)
+		} else if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+// parseReviewIDs parses the IDs of the notes given as arguments.
+func parseReviewIDs(args []string) []int64 {
+	ids := make([]int64, len(args))
+
+	for i, arg := range args {
+		id, err := strconv.ParseInt(strings.TrimPrefix(arg, "#"), 10, 64)
+		if err != nil {
+			log.Fatalf("invalid note ID %q", arg)
+		}
+
+		ids[i] = id
+	}
+
+	return ids
+}
+
+// editInEditor opens content in $EDITOR and returns it once the editor exits.
+func editInEditor(content string) (string, error) {
+	editor := os.Getenv("EDITOR")
+	if editor == "" {
+		editor = "vi"
+	}
+
+	f, err := os.CreateTemp("", "note-*.txt")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.WriteString(content); err != nil {
+		f.Close()
+		return "", err
+	}
+
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+
+	cmd := exec.Command(editor, f.Name())
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", err
+	}
+
+	b, err := os.ReadFile(f.Name())
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(b)), nil
+}
+
+func init() {
+	rootCmd.AddCommand(reviewCmd)
+	rootCmd.AddCommand(publishApprovedCmd)
+	reviewCmd.AddCommand(reviewApproveCmd)
+	reviewCmd.AddCommand(reviewEditCmd)
+	reviewCmd.AddCommand(reviewRejectCmd)
+
+	reviewApproveCmd.Flags().StringVar(&reviewContent, "content", "", "replace the content of the notes before approving them")
+	reviewEditCmd.Flags().StringVar(&reviewContent, "content", "", "new content of the note, $EDITOR is opened if empty")
+	reviewRejectCmd.Flags().BoolVar(&reviewBlock, "block", false, "never propose the repos again")
+
+	reviewCmd.PersistentFlags().StringVar(&channel, "channel", "default", "name of the channel the notes were queued for")
+
+	publishApprovedCmd.Flags().StringVar(&channel, "channel", "default", "name of the channel the notes were queued for")
+	publishApprovedCmd.Flags().IntVar(&approvedQuorum, "quorum", 0, "number of relays that have to acknowledge a note (default 2)")
+	publishApprovedCmd.Flags().BoolVar(&runLock.Disabled, "no-lock", false, "don't take the lock of the channel, letting concurrent runs publish")
+	publishApprovedCmd.Flags().DurationVar(&runLock.Lease, "lock-lease", runLock.Lease, "how long the lock of the channel outlives a run that died")
+	publishApprovedCmd.Flags().DurationVar(&runLock.Wait, "lock-wait", 0, "how long to wait for the lock of the channel held by another run, 0 exits right away")
+}
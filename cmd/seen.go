@@ -0,0 +1,278 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Arturomtz8/github-inspector/pkg/pipeline"
+	"github.com/Arturomtz8/github-inspector/pkg/seen"
+)
+
+var (
+	namespace seen.Namespace
+
+	markRecord seen.Record
+	markTTL    time.Duration
+)
+
+// seenCmd represents the seen command
+var seenCmd = &cobra.Command{
+	Use:   "seen",
+	Short: "Manage the store of published repos",
+	Long: `Manage the store keeping track of the published repos.
+The store is taken from SEEN_STORE, or REDIS_URI if it's not set.`,
+}
+
+// seenMigrateCmd represents the seen migrate command
+var seenMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Move the keys stored without a namespace into one",
+	Long: `Move the keys stored before namespaces were introduced, the bare
+full names of the repos, into the namespace of a sink, channel and language.`,
+	Run: func(_ *cobra.Command, _ []string) {
+		store, err := openSeenStore()
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer store.Close()
+
+		moved, err := seen.Migrate(context.Background(), store, namespace)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		log.Printf("%d keys moved to %s", moved, namespace.Prefix())
+	},
+}
+
+// seenListCmd represents the seen list command
+var seenListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the repos considered published",
+	Run: func(_ *cobra.Command, _ []string) {
+		store, err := openSeenStore()
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer store.Close()
+
+		entries, err := seen.List(context.Background(), store, namespace)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "REPO\tSTARS\tPUBLISHED\tTTL\tEVENT\tBLOCKED")
+
+		for _, entry := range entries {
+			fmt.Fprintln(w, formatEntry(entry))
+		}
+
+		w.Flush()
+	},
+}
+
+// seenGetCmd represents the seen get command
+var seenGetCmd = &cobra.Command{
+	Use:   "get <owner/repo>",
+	Short: "Show the record of a published repo",
+	Args:  cobra.ExactArgs(1),
+	Run: func(_ *cobra.Command, args []string) {
+		store, err := openSeenStore()
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer store.Close()
+
+		entry, ok, err := seen.Get(context.Background(), store, namespace, args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if !ok {
+			log.Fatalf("%s was not published in %s", args[0], namespace.Prefix())
+		}
+
+		fmt.Printf("repo:     %s\n", entry.FullName)
+		fmt.Printf("value:    %s\n", entry.Value)
+		fmt.Printf("ttl:      %s\n", formatTTL(entry.TTL))
+		fmt.Printf("reserved: %t\n", entry.Reserved)
+	},
+}
+
+// seenForgetCmd represents the seen forget command
+var seenForgetCmd = &cobra.Command{
+	Use:   "forget <owner/repo>...",
+	Short: "Forget published repos so they are published again",
+	Long: `Forget published repos, along with any reservation of a running publication,
+so the next run publishes them again.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(_ *cobra.Command, args []string) {
+		store, err := openSeenStore()
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer store.Close()
+
+		for _, fullName := range args {
+			if err := seen.Forget(context.Background(), store, namespace, fullName); err != nil {
+				log.Fatal(err)
+			}
+
+			log.Printf("%s forgotten", fullName)
+		}
+	},
+}
+
+// seenMarkCmd represents the seen mark command
+var seenMarkCmd = &cobra.Command{
+	Use:   "mark <owner/repo>...",
+	Short: "Mark repos as published, or block them for good",
+	Long: `Mark repos as published now, so they are not published until the republish
+policy allows it. With --block, the repos are never published again.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		store, err := openSeenStore()
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer store.Close()
+
+		for _, fullName := range args {
+			record := markRecord
+			record.PublishedAt = time.Now()
+			if record.URL == "" {
+				record.URL = "https://github.com/" + fullName
+			}
+
+			// Without the stars of the repo, the republish policy would see
+			// every popular repo as having gained enough to be published again.
+			if !record.Blocked && !cmd.Flags().Changed("stars") {
+				repo, err := pipeline.FetchRepo(fullName)
+				if err != nil {
+					log.Fatalf("could not get the stars of %s, give them with --stars: %v", fullName, err)
+				}
+
+				record.Stars = repo.StargazersCount
+			}
+
+			if err := seen.Mark(context.Background(), store, namespace, fullName, record, markTTL); err != nil {
+				log.Fatal(err)
+			}
+
+			if record.Blocked {
+				log.Printf("%s blocked", fullName)
+				continue
+			}
+
+			log.Printf("%s marked as published", fullName)
+		}
+	},
+}
+
+// seenExportCmd represents the seen export command
+var seenExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Write the published repos to stdout as JSON lines",
+	Run: func(_ *cobra.Command, _ []string) {
+		store, err := openSeenStore()
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer store.Close()
+
+		exported, err := seen.Export(context.Background(), store, namespace, os.Stdout)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		log.Printf("%d repos exported from %s", exported, namespace.Prefix())
+	},
+}
+
+// seenImportCmd represents the seen import command
+var seenImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Read published repos from stdin as written by export",
+	Run: func(_ *cobra.Command, _ []string) {
+		store, err := openSeenStore()
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer store.Close()
+
+		imported, err := seen.Import(context.Background(), store, namespace, os.Stdin)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		log.Printf("%d repos imported to %s", imported, namespace.Prefix())
+	},
+}
+
+// formatEntry returns the tab separated columns of the entry.
+func formatEntry(entry seen.Entry) string {
+	stars, published, event, blocked := "-", "-", "-", "false"
+
+	if r := entry.Record; r != nil {
+		stars = fmt.Sprint(r.Stars)
+		if !r.PublishedAt.IsZero() {
+			published = r.PublishedAt.Format(time.RFC3339)
+		}
+		if r.EventID != "" {
+			event = r.EventID
+		}
+		blocked = fmt.Sprint(r.Blocked)
+	}
+
+	return fmt.Sprintf("%s\t%s\t%s\t%s\t%s\t%s",
+		entry.FullName, stars, published, formatTTL(entry.TTL), event, blocked)
+}
+
+// formatTTL returns the ttl rounded to the second, or "never" if it never expires.
+func formatTTL(ttl time.Duration) string {
+	if ttl == 0 {
+		return "never"
+	}
+
+	return ttl.Round(time.Second).String()
+}
+
+// openSeenStore opens the store given by SEEN_STORE, or REDIS_URI if it's not set.
+func openSeenStore() (seen.Store, error) {
+	uri := os.Getenv("SEEN_STORE")
+	if uri == "" {
+		uri = os.Getenv("REDIS_URI")
+	}
+
+	return seen.Open(uri)
+}
+
+func init() {
+	rootCmd.AddCommand(seenCmd)
+	seenCmd.AddCommand(seenMigrateCmd)
+	seenCmd.AddCommand(seenListCmd)
+	seenCmd.AddCommand(seenGetCmd)
+	seenCmd.AddCommand(seenForgetCmd)
+	seenCmd.AddCommand(seenMarkCmd)
+	seenCmd.AddCommand(seenExportCmd)
+	seenCmd.AddCommand(seenImportCmd)
+
+	seenCmd.PersistentFlags().StringVar(&namespace.Sink, "sink", "nostr", "sink the repos are published to")
+	seenCmd.PersistentFlags().StringVar(&namespace.Channel, "channel", "default", "channel the repos are published to")
+	seenCmd.PersistentFlags().StringVar(&namespace.Language, "language", "Go", "language of the repos")
+
+	seenMarkCmd.Flags().BoolVar(&markRecord.Blocked, "block", false, "never publish the repos again")
+	seenMarkCmd.Flags().IntVar(&markRecord.Stars, "stars", 0, "stars the repos were published with (default their current stars)")
+	seenMarkCmd.Flags().StringVar(&markRecord.URL, "url", "", "URL of the repo, defaults to its GitHub URL")
+	seenMarkCmd.Flags().DurationVar(&markTTL, "ttl", 365*24*time.Hour, "how long the repos are kept as published")
+}
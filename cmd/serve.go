@@ -0,0 +1,201 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	// The container image may not ship the time zone database.
+	_ "time/tzdata"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Arturomtz8/github-inspector/pkg/bluesky"
+	"github.com/Arturomtz8/github-inspector/pkg/github"
+	"github.com/Arturomtz8/github-inspector/pkg/mastodon"
+	"github.com/Arturomtz8/github-inspector/pkg/nostr"
+	"github.com/Arturomtz8/github-inspector/pkg/pipeline"
+	"github.com/Arturomtz8/github-inspector/pkg/redisclient"
+	"github.com/Arturomtz8/github-inspector/pkg/schedule"
+)
+
+var serveConfigFile string
+
+// defaultServeConfig runs what the GitHub Actions workflow used to run.
+var defaultServeConfig = schedule.Config{
+	CatchUp: schedule.Duration(6 * time.Hour),
+	Jobs: []schedule.JobSpec{
+		{Task: "publish", Schedule: "0 16 * * *", Language: "Go"},
+		{Task: "subscriptions", Schedule: "5 16 * * *"},
+		{Task: "flush", Schedule: "*/30 * * * *"},
+	},
+}
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Run the jobs of the bot on cron schedules",
+	Long: `Run the jobs of the bot on cron schedules until it's stopped.
+The jobs are read from --config, a JSON file like:
+
+  {
+    "timezone": "America/Mexico_City",
+    "jitter": "5m",
+    "catch_up": "6h",
+    "jobs": [
+      {"task": "publish", "schedule": "0 11 * * *", "language": "Go", "threaded": true},
+      {"name": "digest-rust", "task": "digest", "schedule": "0 18 * * fri", "language": "Rust", "since": "weekly"},
+      {"task": "flush", "schedule": "*/30 * * * *"}
+    ]
+  }
+
+The tasks are scrape (queue the notes for review), publish, publish-approved,
+digest, flush, subscriptions, mastodon (post the repos to Mastodon) and bluesky
+(post the repos to Bluesky). Without --config, the repos are published
+at 16:00 UTC. Several instances can run, a Redis lock lets a single one run the jobs.`,
+	Run: func(_ *cobra.Command, _ []string) {
+		config := &defaultServeConfig
+		if serveConfigFile != "" {
+			var err error
+			if config, err = schedule.LoadConfig(serveConfigFile); err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		jobs, err := config.NewJobs(serveTasks())
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		rdb, err := redisclient.Connect(ctx, os.Getenv("REDIS_URI"))
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer rdb.Close()
+
+		if err := schedule.New(rdb, jobs...).Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			log.Fatal(err)
+		}
+	},
+}
+
+// serveTasks returns the tasks the jobs can run, by name.
+// The settings are read again on every run, so changes to the templates
+// or the filter apply without a restart.
+func serveTasks() map[string]schedule.Task {
+	publish := func(review bool) schedule.Task {
+		return func(ctx context.Context, spec schedule.JobSpec) error {
+			tmpls, err := loadTemplates("nostr")
+			if err != nil {
+				return err
+			}
+
+			policy, err := loadFilter()
+			if err != nil {
+				return err
+			}
+
+			overrides, err := loadCuration(curationFile)
+			if err != nil {
+				return err
+			}
+
+			return nostr.PusblishRepos(ctx, os.Getenv("NOSTR_HEX_SK"), os.Getenv("REDIS_URI"), nostr.PublishOptions{
+				TrendingOptions: pipeline.TrendingOptions{
+					Language:  spec.Language,
+					Channel:   spec.Channel,
+					SeenStore: os.Getenv("SEEN_STORE"),
+					Templates: tmpls,
+					Filter:    policy,
+					Curation:  overrides,
+				},
+				Threaded: spec.Threaded,
+				Review:   review,
+			})
+		}
+	}
+
+	return map[string]schedule.Task{
+		"scrape":  publish(true),
+		"publish": publish(false),
+		"publish-approved": func(ctx context.Context, spec schedule.JobSpec) error {
+			return nostr.PublishApproved(ctx, os.Getenv("NOSTR_HEX_SK"), os.Getenv("REDIS_URI"), os.Getenv("SEEN_STORE"),
+				spec.Channel, 0, nostr.DefaultRunLockOptions())
+		},
+		"digest": func(ctx context.Context, spec schedule.JobSpec) error {
+			opts := nostr.DefaultDigestOptions()
+			if spec.Language != "" {
+				tmpls, err := loadTemplates("nostr")
+				if err != nil {
+					return err
+				}
+
+				opts.Language = spec.Language
+				opts.Hashtags = tmpls.Languages().Hashtags(spec.Language)
+			}
+			if spec.Since != "" {
+				opts.Since = spec.Since
+			}
+
+			repos, err := github.GetTrendingRepos(opts.Since, opts.Language)
+			if err != nil {
+				return err
+			}
+
+			overrides, err := loadCuration(curationFile)
+			if err != nil {
+				return err
+			}
+			repos.Items = overrides.Merge(repos.Items, pipeline.FetchRepo)
+
+			return nostr.PublishDigest(ctx, os.Getenv("NOSTR_HEX_SK"), repos, opts)
+		},
+		"mastodon": func(ctx context.Context, spec schedule.JobSpec) error {
+			opts, err := mastodonOptions(spec.Language, spec.Channel)
+			if err != nil {
+				return err
+			}
+
+			return mastodon.PublishRepos(ctx, newMastodonClient(), os.Getenv("REDIS_URI"), opts)
+		},
+		"bluesky": func(ctx context.Context, spec schedule.JobSpec) error {
+			opts, err := blueskyOptions(spec.Language, spec.Channel)
+			if err != nil {
+				return err
+			}
+
+			return bluesky.PublishRepos(ctx, newBlueskyClient(), os.Getenv("REDIS_URI"), opts)
+		},
+		"flush": func(ctx context.Context, _ schedule.JobSpec) error {
+			return nostr.FlushOutbox(ctx, os.Getenv("REDIS_URI"), os.Getenv("SEEN_STORE"), false)
+		},
+		"subscriptions": func(ctx context.Context, _ schedule.JobSpec) error {
+			tmpls, err := loadTemplates("nostr")
+			if err != nil {
+				return err
+			}
+
+			return nostr.SendSubscriptions(ctx, os.Getenv("NOSTR_HEX_SK"), os.Getenv("REDIS_URI"), tmpls.Languages())
+		},
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().StringVar(&serveConfigFile, "config", "", "JSON file with the jobs and their schedules")
+	serveCmd.Flags().StringVar(&templateOpts.Dir, "template-dir", "", "directory of templates overriding the embedded ones (default ~/.config/github-inspector/templates)")
+	serveCmd.Flags().StringVar(&templateOpts.Languages, "languages", "", "JSON file mapping languages to their hashtags, emoji and template variant")
+	serveCmd.Flags().StringVar(&filterFile, "filter", "", "JSON file with the rules the repos have to pass to be published")
+	serveCmd.Flags().StringVar(&curationFile, "curation", "", "JSON file pinning repos and overriding their descriptions and comments")
+}
@@ -8,6 +8,7 @@ require (
 	github.com/redis/go-redis/v9 v9.1.0
 	github.com/spf13/cobra v1.7.0
 	github.com/stretchr/testify v1.8.4
+	go.etcd.io/bbolt v1.3.8
 	golang.org/x/time v0.3.0
 )
 
@@ -0,0 +1,122 @@
+package bluesky
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/Arturomtz8/github-inspector/pkg/pipeline"
+	"github.com/Arturomtz8/github-inspector/pkg/seen"
+)
+
+// sinkName names the posts in the seen store and the template directory.
+const sinkName = "bluesky"
+
+// Options changes the way PublishRepos publishes the repos.
+type Options struct {
+	pipeline.TrendingOptions
+	// PostLanguage is the ISO 639 code of the language of the posts, e.g. "en".
+	// Empty leaves it out.
+	PostLanguage string
+}
+
+// newPostLimiter returns the limiter of the posts,
+// well within the 1666 records an hour a PDS lets an account create.
+func newPostLimiter() *rate.Limiter {
+	return rate.NewLimiter(rate.Every(time.Minute), 5)
+}
+
+// PublishRepos gets the trending repos and posts the ones not published yet
+// to the account of the client.
+// redisURI is the seen store used when opts.SeenStore is empty.
+func PublishRepos(ctx context.Context, client *Client, redisURI string, opts Options) error {
+	if opts.SeenStore == "" {
+		opts.SeenStore = redisURI
+	}
+
+	if opts.SeenStore == "" {
+		return errors.New("no seen store, set the Redis URI or the seen store")
+	}
+
+	if !opts.DryRun && (client == nil || client.Identifier == "" || client.Password == "") {
+		return errors.New("the handle and the app password are required")
+	}
+
+	if !opts.DryRun {
+		// A wrong password fails the run before anything is reserved.
+		if _, err := client.CreateSession(ctx); err != nil {
+			return err
+		}
+	}
+
+	sink := &pipeline.Sink{Name: sinkName, Limiter: newPostLimiter()}
+
+	run, err := pipeline.NewTrending(opts.TrendingOptions, sink)
+	if err != nil {
+		return err
+	}
+	defer run.Close()
+
+	sink.Publisher = &publisher{
+		client:   client,
+		seen:     sink.Seen,
+		language: opts.PostLanguage,
+		now:      time.Now,
+		dry:      opts.DryRunOutput(),
+	}
+
+	return run.Run(ctx)
+}
+
+// publisher is the Bluesky sink of the pipeline, it creates the posts as records.
+type publisher struct {
+	client *Client
+	seen   *pipeline.Seen
+	// language is the language of every post.
+	language string
+	now      func() time.Time
+	// dry prints the posts instead of creating them, if set.
+	dry io.Writer
+}
+
+// Publish creates the record of the post and commits its repo once the PDS stored it.
+func (p *publisher) Publish(ctx context.Context, post *pipeline.Post) error {
+	record := NewPost(post.Repo, post.Content, p.language, p.now())
+
+	if p.dry != nil {
+		return p.print(record)
+	}
+
+	created, err := p.client.CreateRecord(ctx, PostCollection, record)
+	if err != nil {
+		p.seen.Unreserve(ctx, post.Key)
+		return err
+	}
+
+	log.Printf("published to %s", created.URI)
+
+	return p.seen.Commit(ctx, post.Key, p.seen.Token(), seen.Record{
+		URL:         post.Repo.HtmlURL,
+		Stars:       post.Repo.StargazersCount,
+		PublishedAt: time.Now(),
+		EventID:     created.URI,
+	})
+}
+
+// print writes the record of the post to the output of the dry run, as JSON.
+func (p *publisher) print(record *Post) error {
+	b, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(p.dry, "%s\n", b)
+
+	return err
+}
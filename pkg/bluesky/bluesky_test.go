@@ -0,0 +1,86 @@
+package bluesky
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/Arturomtz8/github-inspector/pkg/github"
+	"github.com/Arturomtz8/github-inspector/pkg/pipeline"
+	"github.com/Arturomtz8/github-inspector/pkg/seen"
+)
+
+func TestPublish(t *testing.T) {
+	ctx := context.Background()
+
+	p := &pds{t: t, password: "app-password"}
+	s := httptest.NewServer(p)
+	defer s.Close()
+
+	store := seen.NewMemoryStore()
+	seenRepos := pipeline.NewSeen(store, seen.Namespace{Sink: sinkName, Channel: "test", Language: "Go"})
+
+	pub := &publisher{
+		client:   NewClient(s.URL, "bot.example.com", "app-password"),
+		seen:     seenRepos,
+		language: "en",
+		now:      time.Now,
+	}
+
+	repos := []*github.RepoTrending{
+		{FullName: "foo/bar", HtmlURL: "https://github.com/foo/bar", StargazersCount: 100},
+		{FullName: "foo/baz", HtmlURL: "https://github.com/foo/baz", StargazersCount: 200},
+	}
+
+	candidates, err := pipeline.Dedupe(ctx, seenRepos, pipeline.DefaultRepublishPolicy(), repos)
+	require.NoError(t, err)
+	require.Len(t, candidates, 2)
+
+	require.NoError(t, pub.Publish(ctx, &pipeline.Post{Candidate: candidates[0], Content: "foo/bar\nhttps://github.com/foo/bar\n#golang"}))
+
+	// the PDS is down for the second one.
+	s.Close()
+	require.Error(t, pub.Publish(ctx, &pipeline.Post{Candidate: candidates[1], Content: "foo/baz"}))
+
+	require.Len(t, p.records, 1)
+	require.Equal(t, []any{"en"}, p.records[0]["langs"])
+	require.Len(t, p.records[0]["facets"], 2)
+	require.Equal(t, "https://github.com/foo/bar", p.records[0]["embed"].(map[string]any)["external"].(map[string]any)["uri"])
+
+	// the published repo is committed with the URI of its record.
+	value, ok, err := store.Get(ctx, candidates[0].Key)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	record, ok := seen.ParseRecord(value)
+	require.True(t, ok)
+	require.Equal(t, "at://did:plc:bot/app.bsky.feed.post/3k1", record.EventID)
+	require.Equal(t, 100, record.Stars)
+
+	// the repo that failed is released, a later run can publish it.
+	_, ok, err = store.Get(ctx, candidates[1].Key)
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+func TestPublishDryRun(t *testing.T) {
+	var buf bytes.Buffer
+	pub := &publisher{
+		seen: pipeline.NewSeen(seen.NewMemoryStore(), seen.Namespace{}),
+		now:  func() time.Time { return time.Date(2023, 5, 1, 16, 0, 0, 0, time.UTC) },
+		dry:  &buf,
+	}
+
+	c := &pipeline.Candidate{Repo: &github.RepoTrending{FullName: "foo/bar"}}
+	require.NoError(t, pub.Publish(context.Background(), &pipeline.Post{Candidate: c, Content: "foo/bar: a good project #golang"}))
+	require.JSONEq(t, `{
+		"$type": "app.bsky.feed.post",
+		"text": "foo/bar: a good project #golang",
+		"createdAt": "2023-05-01T16:00:00Z",
+		"facets": [{"index": {"byteStart": 24, "byteEnd": 31}, "features": [{"$type": "app.bsky.richtext.facet#tag", "tag": "golang"}]}]
+	}`, buf.String())
+}
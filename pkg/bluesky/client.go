@@ -0,0 +1,269 @@
+// Package bluesky publishes the trending repos as Bluesky posts,
+// through the XRPC API of a PDS (Personal Data Server).
+package bluesky
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultHost is the PDS of the accounts hosted by Bluesky.
+	DefaultHost = "https://bsky.social"
+
+	createSessionPath  = "/xrpc/com.atproto.server.createSession"
+	refreshSessionPath = "/xrpc/com.atproto.server.refreshSession"
+	createRecordPath   = "/xrpc/com.atproto.repo.createRecord"
+
+	// maxRetries is the number of times a record is created again after hitting the rate limit.
+	maxRetries = 2
+	// defaultRetryAfter is the wait after hitting the rate limit of a PDS that doesn't tell when it resets.
+	defaultRetryAfter = time.Minute
+)
+
+// Session is the session of the account, the access token authenticates the requests
+// and the refresh token gets a new one once it expires.
+type Session struct {
+	AccessJwt  string `json:"accessJwt"`
+	RefreshJwt string `json:"refreshJwt"`
+	DID        string `json:"did"`
+	Handle     string `json:"handle"`
+}
+
+// CreatedRecord is a record stored by the PDS.
+type CreatedRecord struct {
+	URI string `json:"uri"`
+	CID string `json:"cid"`
+}
+
+// xrpcError is the body of the errors of the API.
+type xrpcError struct {
+	Error   string `json:"error"`
+	Message string `json:"message"`
+}
+
+// APIError is an error the PDS replied with.
+type APIError struct {
+	StatusCode int
+	// Name is the name of the error, e.g. ExpiredToken.
+	Name    string
+	Message string
+}
+
+func (e *APIError) Error() string {
+	if e.Name == "" {
+		return fmt.Sprintf("xrpc: invalid request with status %d", e.StatusCode)
+	}
+
+	if e.Message == "" {
+		return fmt.Sprintf("xrpc: %d %s", e.StatusCode, e.Name)
+	}
+
+	return fmt.Sprintf("xrpc: %d %s: %s", e.StatusCode, e.Name, e.Message)
+}
+
+// expired tells whether the access token of the request expired.
+func (e *APIError) expired() bool {
+	return e.Name == "ExpiredToken" || (e.StatusCode == http.StatusUnauthorized && e.Name != "AuthenticationRequired")
+}
+
+// Client creates records on a PDS, with the session of an account logged in with an app password.
+// It refreshes the session once the access token expires, and waits for the rate limit to reset.
+type Client struct {
+	// Host is the URL of the PDS, e.g. https://bsky.social.
+	Host string
+	// Identifier is the handle or the DID of the account.
+	Identifier string
+	// Password is an app password of the account, created in its settings.
+	Password string
+	// HTTP sends the requests. Defaults to http.DefaultClient.
+	HTTP *http.Client
+
+	mu      sync.Mutex
+	session *Session
+	reset   time.Time
+}
+
+// NewClient returns a client of the PDS, logging in with the identifier and the app password.
+// An empty host defaults to DefaultHost.
+func NewClient(host, identifier, password string) *Client {
+	if host == "" {
+		host = DefaultHost
+	}
+
+	return &Client{Host: strings.TrimSuffix(host, "/"), Identifier: identifier, Password: password}
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTP == nil {
+		return http.DefaultClient
+	}
+
+	return c.HTTP
+}
+
+// CreateSession logs in with the app password, the session is kept for the next requests.
+func (c *Client) CreateSession(ctx context.Context) (*Session, error) {
+	body := map[string]string{"identifier": c.Identifier, "password": c.Password}
+
+	var session Session
+	if err := c.call(ctx, createSessionPath, "", body, &session); err != nil {
+		return nil, fmt.Errorf("creating session: %w", err)
+	}
+
+	c.mu.Lock()
+	c.session = &session
+	c.mu.Unlock()
+
+	return &session, nil
+}
+
+// refreshSession gets a new access token with the refresh token of the session,
+// or logs in again if the refresh token expired too.
+func (c *Client) refreshSession(ctx context.Context, old *Session) (*Session, error) {
+	var session Session
+	if err := c.call(ctx, refreshSessionPath, old.RefreshJwt, nil, &session); err != nil {
+		return c.CreateSession(ctx)
+	}
+
+	c.mu.Lock()
+	c.session = &session
+	c.mu.Unlock()
+
+	return &session, nil
+}
+
+// currentSession returns the session of the client, logging in if there's none yet.
+func (c *Client) currentSession(ctx context.Context) (*Session, error) {
+	c.mu.Lock()
+	session := c.session
+	c.mu.Unlock()
+
+	if session != nil {
+		return session, nil
+	}
+
+	return c.CreateSession(ctx)
+}
+
+// CreateRecord stores the record in the collection of the repo of the account.
+func (c *Client) CreateRecord(ctx context.Context, collection string, record any) (*CreatedRecord, error) {
+	session, err := c.currentSession(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshed := false
+	for attempt := 0; ; attempt++ {
+		body := map[string]any{
+			"repo":       session.DID,
+			"collection": collection,
+			"record":     record,
+		}
+
+		var created CreatedRecord
+		err := c.call(ctx, createRecordPath, session.AccessJwt, body, &created)
+		if err == nil {
+			return &created, nil
+		}
+
+		var apiErr *APIError
+		ok := errors.As(err, &apiErr)
+		switch {
+		case ok && apiErr.expired() && !refreshed:
+			refreshed = true
+			if session, err = c.refreshSession(ctx, session); err != nil {
+				return nil, err
+			}
+		case ok && apiErr.StatusCode == http.StatusTooManyRequests && attempt < maxRetries:
+			// the wait is set by call, the record is created again once the limit resets.
+		default:
+			return nil, fmt.Errorf("creating record: %w", err)
+		}
+	}
+}
+
+// call sends a procedure to the PDS, authenticated with the token if any, and decodes its output.
+func (c *Client) call(ctx context.Context, path, token string, input, output any) error {
+	if err := c.waitLimit(ctx); err != nil {
+		return err
+	}
+
+	var body bytes.Buffer
+	if input != nil {
+		if err := json.NewEncoder(&body).Encode(input); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.Host+path, &body)
+	if err != nil {
+		return err
+	}
+
+	if input != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		c.exhaust(resp.Header)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var xErr xrpcError
+		json.NewDecoder(resp.Body).Decode(&xErr)
+
+		return &APIError{StatusCode: resp.StatusCode, Name: xErr.Error, Message: xErr.Message}
+	}
+
+	return json.NewDecoder(resp.Body).Decode(output)
+}
+
+// exhaust records when the rate limit resets, after the PDS replied 429.
+func (c *Client) exhaust(h http.Header) {
+	// The PDS sends the time of the reset as a Unix timestamp.
+	reset := time.Now().Add(defaultRetryAfter)
+	if unix, err := strconv.ParseInt(h.Get("RateLimit-Reset"), 10, 64); err == nil {
+		reset = time.Unix(unix, 0)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.reset = reset
+}
+
+// waitLimit waits for the rate limit to reset if the PDS said no request is left.
+func (c *Client) waitLimit(ctx context.Context) error {
+	c.mu.Lock()
+	wait := time.Until(c.reset)
+	c.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(wait):
+		return nil
+	}
+}
@@ -0,0 +1,124 @@
+package bluesky
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// pds is a stand-in of a PDS, it hands out numbered access tokens
+// and stores the records created with the last one.
+type pds struct {
+	t        *testing.T
+	password string
+	tokens   int
+	records  []map[string]any
+	// expire makes the current access token expire.
+	expire bool
+	// limited is the number of record creations replied 429.
+	limited int
+}
+
+func (p *pds) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	require.Equal(p.t, http.MethodPost, r.Method)
+
+	switch r.URL.Path {
+	case createSessionPath:
+		var body map[string]string
+		require.NoError(p.t, json.NewDecoder(r.Body).Decode(&body))
+		require.Equal(p.t, "bot.example.com", body["identifier"])
+
+		if body["password"] != p.password {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error": "AuthenticationRequired", "message": "Invalid identifier or password"}`))
+			return
+		}
+
+		p.session(w)
+	case refreshSessionPath:
+		require.Equal(p.t, "Bearer refresh", r.Header.Get("Authorization"))
+		p.expire = false
+		p.session(w)
+	case createRecordPath:
+		if p.expire || r.Header.Get("Authorization") != "Bearer access"+strconv.Itoa(p.tokens) {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": "ExpiredToken", "message": "Token has expired"}`))
+			return
+		}
+
+		if p.limited > 0 {
+			p.limited--
+			w.Header().Set("RateLimit-Reset", strconv.FormatInt(time.Now().Unix(), 10))
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"error": "RateLimitExceeded"}`))
+			return
+		}
+
+		var body map[string]any
+		require.NoError(p.t, json.NewDecoder(r.Body).Decode(&body))
+		require.Equal(p.t, "did:plc:bot", body["repo"])
+		require.Equal(p.t, PostCollection, body["collection"])
+
+		p.records = append(p.records, body["record"].(map[string]any))
+		w.Write([]byte(`{"uri": "at://did:plc:bot/app.bsky.feed.post/3k` + strconv.Itoa(len(p.records)) + `", "cid": "bafy"}`))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (p *pds) session(w http.ResponseWriter) {
+	p.tokens++
+	w.Write([]byte(`{"accessJwt": "access` + strconv.Itoa(p.tokens) + `", "refreshJwt": "refresh", "did": "did:plc:bot", "handle": "bot.example.com"}`))
+}
+
+func TestCreateSession(t *testing.T) {
+	s := httptest.NewServer(&pds{t: t, password: "app-password"})
+	defer s.Close()
+
+	session, err := NewClient(s.URL+"/", "bot.example.com", "app-password").CreateSession(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "did:plc:bot", session.DID)
+	require.Equal(t, "access1", session.AccessJwt)
+
+	_, err = NewClient(s.URL, "bot.example.com", "wrong").CreateSession(context.Background())
+	require.ErrorContains(t, err, "Invalid identifier or password")
+}
+
+func TestCreateRecord(t *testing.T) {
+	p := &pds{t: t, password: "app-password"}
+	s := httptest.NewServer(p)
+	defer s.Close()
+
+	c := NewClient(s.URL, "bot.example.com", "app-password")
+	ctx := context.Background()
+
+	// the client logs in on the first record.
+	created, err := c.CreateRecord(ctx, PostCollection, map[string]string{"text": "foo/bar"})
+	require.NoError(t, err)
+	require.Equal(t, "at://did:plc:bot/app.bsky.feed.post/3k1", created.URI)
+
+	// an expired access token is refreshed, and the record created again.
+	p.expire = true
+	_, err = c.CreateRecord(ctx, PostCollection, map[string]string{"text": "foo/baz"})
+	require.NoError(t, err)
+	require.Equal(t, 2, p.tokens)
+
+	// a rate limited record is created again once the limit resets.
+	p.limited = 1
+	_, err = c.CreateRecord(ctx, PostCollection, map[string]string{"text": "foo/qux"})
+	require.NoError(t, err)
+
+	require.Len(t, p.records, 3)
+	require.Equal(t, "foo/qux", p.records[2]["text"])
+
+	// the rate limit isn't retried forever.
+	p.limited = maxRetries + 1
+	_, err = c.CreateRecord(ctx, PostCollection, map[string]string{"text": "foo/quux"})
+	require.ErrorContains(t, err, "RateLimitExceeded")
+}
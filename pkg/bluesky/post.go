@@ -0,0 +1,145 @@
+package bluesky
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Arturomtz8/github-inspector/pkg/github"
+	"github.com/Arturomtz8/github-inspector/pkg/templates"
+)
+
+const (
+	// PostCollection is the collection of the posts in the repo of an account.
+	PostCollection = "app.bsky.feed.post"
+	// MaxGraphemes is the length limit of the text of a post.
+	MaxGraphemes = 300
+
+	linkFeature   = "app.bsky.richtext.facet#link"
+	tagFeature    = "app.bsky.richtext.facet#tag"
+	externalEmbed = "app.bsky.embed.external"
+
+	// maxTagLength is the length limit of a hashtag, longer ones aren't tagged.
+	maxTagLength = 64
+	// maxCardDescription is the length the description of a link card is truncated to.
+	maxCardDescription = 280
+)
+
+var (
+	urlPattern = regexp.MustCompile(`https?://[^\s]+`)
+	tagPattern = regexp.MustCompile(`(?:^|\s)(#[\p{L}\p{N}_]+)`)
+	digits     = regexp.MustCompile(`^#\d+$`)
+)
+
+// Post is an app.bsky.feed.post record.
+type Post struct {
+	Type      string    `json:"$type"`
+	Text      string    `json:"text"`
+	CreatedAt string    `json:"createdAt"`
+	Langs     []string  `json:"langs,omitempty"`
+	Facets    []Facet   `json:"facets,omitempty"`
+	Embed     *External `json:"embed,omitempty"`
+}
+
+// Facet annotates a range of the text of a post, given in bytes of its UTF-8 encoding.
+type Facet struct {
+	Index    ByteSlice `json:"index"`
+	Features []Feature `json:"features"`
+}
+
+// ByteSlice is the range of bytes [ByteStart, ByteEnd) of the text a facet annotates.
+type ByteSlice struct {
+	ByteStart int `json:"byteStart"`
+	ByteEnd   int `json:"byteEnd"`
+}
+
+// Feature is what a facet makes of its range: a link to URI or a hashtag Tag, without the #.
+type Feature struct {
+	Type string `json:"$type"`
+	URI  string `json:"uri,omitempty"`
+	Tag  string `json:"tag,omitempty"`
+}
+
+// External is the link card shown under a post.
+type External struct {
+	Type     string       `json:"$type"`
+	External ExternalLink `json:"external"`
+}
+
+// ExternalLink is the page of a link card.
+type ExternalLink struct {
+	URI         string `json:"uri"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+}
+
+// NewPost returns the post of the rendered text of the repo: the text is fitted to MaxGraphemes,
+// its links and hashtags are made facets and the repo is shown as a link card.
+// language is the ISO 639 code of the language of the post, empty leaves it out.
+func NewPost(repo *github.RepoTrending, text, language string, createdAt time.Time) *Post {
+	text = templates.Fit(text, MaxGraphemes, templates.RuneCount)
+
+	post := &Post{
+		Type:      PostCollection,
+		Text:      text,
+		CreatedAt: createdAt.UTC().Format(time.RFC3339),
+		Facets:    Facets(text),
+		Embed:     linkCard(repo),
+	}
+
+	if language != "" {
+		post.Langs = []string{language}
+	}
+
+	return post
+}
+
+// Facets returns the facets of the links and the hashtags of the text.
+func Facets(text string) []Facet {
+	var facets []Facet
+
+	for _, loc := range urlPattern.FindAllStringIndex(text, -1) {
+		// the punctuation ending a sentence isn't part of the link.
+		uri := strings.TrimRight(text[loc[0]:loc[1]], ".,;:!?)")
+
+		facets = append(facets, Facet{
+			Index:    ByteSlice{ByteStart: loc[0], ByteEnd: loc[0] + len(uri)},
+			Features: []Feature{{Type: linkFeature, URI: uri}},
+		})
+	}
+
+	for _, loc := range tagPattern.FindAllStringSubmatchIndex(text, -1) {
+		tag := text[loc[2]:loc[3]]
+		if digits.MatchString(tag) || len([]rune(tag))-1 > maxTagLength {
+			continue
+		}
+
+		facets = append(facets, Facet{
+			Index:    ByteSlice{ByteStart: loc[2], ByteEnd: loc[3]},
+			Features: []Feature{{Type: tagFeature, Tag: strings.TrimPrefix(tag, "#")}},
+		})
+	}
+
+	return facets
+}
+
+// linkCard returns the link card of the repo, nil if it has no URL.
+func linkCard(repo *github.RepoTrending) *External {
+	if repo == nil || repo.HtmlURL == "" {
+		return nil
+	}
+
+	description := repo.Description
+	if runes := []rune(description); len(runes) > maxCardDescription {
+		description = strings.TrimSpace(string(runes[:maxCardDescription-1])) + "…"
+	}
+
+	return &External{
+		Type: externalEmbed,
+		External: ExternalLink{
+			URI:         repo.HtmlURL,
+			Title:       repo.FullName,
+			Description: description,
+		},
+	}
+}
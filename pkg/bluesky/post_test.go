@@ -0,0 +1,65 @@
+package bluesky
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/Arturomtz8/github-inspector/pkg/github"
+	"github.com/Arturomtz8/github-inspector/pkg/templates"
+)
+
+func TestFacets(t *testing.T) {
+	text := "foo/bar: a good project\n⭐: 12.3k\nhttps://github.com/foo/bar\n#golang #cli #2023"
+
+	facets := Facets(text)
+	require.Len(t, facets, 3)
+
+	// the offsets are in bytes, ⭐ takes three of them.
+	link := facets[0]
+	require.Equal(t, ByteSlice{ByteStart: 35, ByteEnd: 61}, link.Index)
+	require.Equal(t, "https://github.com/foo/bar", text[link.Index.ByteStart:link.Index.ByteEnd])
+	require.Equal(t, []Feature{{Type: linkFeature, URI: "https://github.com/foo/bar"}}, link.Features)
+
+	require.Equal(t, "#golang", text[facets[1].Index.ByteStart:facets[1].Index.ByteEnd])
+	require.Equal(t, []Feature{{Type: tagFeature, Tag: "golang"}}, facets[1].Features)
+	require.Equal(t, "#cli", text[facets[2].Index.ByteStart:facets[2].Index.ByteEnd])
+
+	// the punctuation after a link and the anchors of a link aren't part of the facets.
+	facets = Facets("see https://example.com/#readme.")
+	require.Len(t, facets, 1)
+	require.Equal(t, "https://example.com/#readme", facets[0].Features[0].URI)
+}
+
+func TestNewPost(t *testing.T) {
+	repo := &github.RepoTrending{
+		FullName:    "foo/bar",
+		Description: "a good project",
+		HtmlURL:     "https://github.com/foo/bar",
+	}
+
+	text := "foo/bar: " + strings.Repeat("a good project ", 30) + "\nhttps://github.com/foo/bar\n#golang #programming"
+	post := NewPost(repo, text, "en", time.Date(2023, 5, 1, 16, 0, 0, 0, time.FixedZone("CST", -6*3600)))
+
+	require.Equal(t, PostCollection, post.Type)
+	require.Equal(t, "2023-05-01T22:00:00Z", post.CreatedAt)
+	require.Equal(t, []string{"en"}, post.Langs)
+
+	// the text fits the limit, the link stays.
+	require.LessOrEqual(t, templates.RuneCount(post.Text), MaxGraphemes)
+	require.Contains(t, post.Text, "\nhttps://github.com/foo/bar\n")
+	require.Equal(t, Facets(post.Text), post.Facets)
+
+	require.Equal(t, &External{
+		Type: externalEmbed,
+		External: ExternalLink{
+			URI:         "https://github.com/foo/bar",
+			Title:       "foo/bar",
+			Description: "a good project",
+		},
+	}, post.Embed)
+
+	require.Nil(t, NewPost(&github.RepoTrending{}, "foo", "", time.Now()).Embed)
+}
@@ -0,0 +1,145 @@
+// Package curation lets editors pin repos and override what the trending page says about them.
+package curation
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/Arturomtz8/github-inspector/pkg/github"
+)
+
+// Override is what the editors say about a repo.
+type Override struct {
+	// Pinned repos are always included, even when they aren't trending
+	// or the filter would keep them out.
+	Pinned bool `json:"pinned"`
+	// Comment is a one-line comment shown along with the repo.
+	Comment string `json:"comment"`
+	// Description replaces the description of the repo.
+	Description string `json:"description"`
+}
+
+// Curation holds the overrides of the repos, by full name ("owner/name").
+// The zero value changes nothing.
+type Curation struct {
+	Repos map[string]Override `json:"repos"`
+}
+
+// Fetcher returns the repo with the given full name,
+// it's used to get the pinned repos that aren't trending.
+type Fetcher func(fullName string) (*github.RepoTrending, error)
+
+// Load reads the curation from a JSON file.
+func Load(path string) (*Curation, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var c Curation
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	for name := range c.Repos {
+		if owner, repo, ok := strings.Cut(name, "/"); !ok || owner == "" || repo == "" {
+			return nil, fmt.Errorf("parsing %s: invalid repo name %q, it has to be owner/name", path, name)
+		}
+	}
+
+	return &c, nil
+}
+
+// lookup returns the override of the repo, GitHub names ignore case.
+func (c *Curation) lookup(fullName string) (Override, bool) {
+	if c == nil {
+		return Override{}, false
+	}
+
+	if o, ok := c.Repos[fullName]; ok {
+		return o, true
+	}
+
+	for name, o := range c.Repos {
+		if strings.EqualFold(name, fullName) {
+			return o, true
+		}
+	}
+
+	return Override{}, false
+}
+
+// Pinned returns the full names of the pinned repos, sorted.
+func (c *Curation) Pinned() []string {
+	if c == nil {
+		return nil
+	}
+
+	var pinned []string
+	for name, o := range c.Repos {
+		if o.Pinned {
+			pinned = append(pinned, name)
+		}
+	}
+
+	sort.Strings(pinned)
+
+	return pinned
+}
+
+// Merge applies the overrides to the repos and adds the pinned repos missing from them,
+// fetched with fetch, ahead of the others. A pinned repo that can't be fetched is skipped.
+// It works on a nil receiver, returning the repos unchanged.
+func (c *Curation) Merge(repos []*github.RepoTrending, fetch Fetcher) []*github.RepoTrending {
+	if c == nil || len(c.Repos) == 0 {
+		return repos
+	}
+
+	known := make(map[string]bool, len(repos))
+	for _, repo := range repos {
+		known[strings.ToLower(repo.FullName)] = true
+	}
+
+	var pinned []*github.RepoTrending
+	for _, name := range c.Pinned() {
+		if known[strings.ToLower(name)] {
+			continue
+		}
+
+		repo, err := fetch(name)
+		if err != nil {
+			log.Printf("error occurred fetching pinned repo %s: %v", name, err)
+			continue
+		}
+
+		known[strings.ToLower(name)] = true
+		pinned = append(pinned, repo)
+	}
+
+	merged := append(pinned, repos...)
+	for _, repo := range merged {
+		c.Apply(repo)
+	}
+
+	return merged
+}
+
+// Apply sets the comment and the description of the repo from its override, if any.
+func (c *Curation) Apply(repo *github.RepoTrending) {
+	o, ok := c.lookup(repo.FullName)
+	if !ok {
+		return
+	}
+
+	if o.Comment != "" {
+		repo.Comment = o.Comment
+	}
+
+	if o.Description != "" {
+		repo.Description = o.Description
+	}
+}
@@ -0,0 +1,72 @@
+package curation
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/Arturomtz8/github-inspector/pkg/github"
+)
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "curation.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"repos": {"foo/bar": {"pinned": true, "comment": "a must"}}}`), 0o644))
+
+	c, err := Load(path)
+	require.NoError(t, err)
+	require.Equal(t, Override{Pinned: true, Comment: "a must"}, c.Repos["foo/bar"])
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"repos": {"foo": {"pinned": true}}}`), 0o644))
+	_, err = Load(path)
+	require.Error(t, err)
+}
+
+func TestMerge(t *testing.T) {
+	c := &Curation{Repos: map[string]Override{
+		"Foo/Bar":     {Comment: "worth a look", Description: "a better description"},
+		"foo/pinned":  {Pinned: true, Comment: "our pick"},
+		"foo/missing": {Pinned: true},
+		"foo/trend":   {Pinned: true},
+	}}
+
+	fetched := map[string]bool{}
+	fetch := func(fullName string) (*github.RepoTrending, error) {
+		fetched[fullName] = true
+		if fullName == "foo/missing" {
+			return nil, errors.New("not found")
+		}
+
+		return &github.RepoTrending{FullName: fullName, Description: "pinned"}, nil
+	}
+
+	repos := []*github.RepoTrending{
+		{FullName: "foo/bar", Description: ""},
+		{FullName: "foo/trend", Description: "trending"},
+		{FullName: "foo/other", Description: "untouched"},
+	}
+
+	merged := c.Merge(repos, fetch)
+	require.Len(t, merged, 4)
+
+	require.Equal(t, "foo/pinned", merged[0].FullName)
+	require.Equal(t, "our pick", merged[0].Comment)
+
+	require.Equal(t, "foo/bar", merged[1].FullName)
+	require.Equal(t, "a better description", merged[1].Description)
+	require.Equal(t, "worth a look", merged[1].Comment)
+
+	require.Equal(t, "trending", merged[2].Description)
+	require.Equal(t, "untouched", merged[3].Description)
+	require.Empty(t, merged[3].Comment)
+
+	// the pinned repos already trending aren't fetched.
+	require.Equal(t, map[string]bool{"foo/pinned": true, "foo/missing": true}, fetched)
+
+	// a nil curation changes nothing.
+	var nilCuration *Curation
+	require.Equal(t, repos, nilCuration.Merge(repos, fetch))
+	require.Nil(t, nilCuration.Pinned())
+}
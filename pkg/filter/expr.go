@@ -0,0 +1,623 @@
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/Arturomtz8/github-inspector/pkg/github"
+)
+
+// Expr is a compiled boolean expression over the fields of a repo, e.g.
+//
+//	stars > 300 && language == "Go" && !archived && any(topics, # == "cli")
+//
+// It supports:
+//
+//	literals      100, "text", true, false
+//	logic         &&, ||, !, parentheses
+//	comparison    ==, !=, <, <=, >, >=
+//	strings       contains, startsWith, endsWith, matches (a regular expression)
+//	lists         "cli" in topics, any(topics, # == "cli"), all(topics, # != "crypto"), len(topics)
+//	functions     lower(description), len(description)
+//
+// Inside any and all, # is the current element of the list.
+// The expression is type checked when it's compiled, naming any unknown field.
+// The trending page only shows some of the fields, see NeedsDetails.
+type Expr struct {
+	src  string
+	eval evalFunc
+	// details tells whether the expression uses a field missing from the trending page.
+	details bool
+}
+
+// Compile parses and type checks the expression.
+func Compile(src string) (*Expr, error) {
+	p := &parser{lex: lexer{src: src}}
+	p.next()
+
+	n, err := p.parseOr()
+	if p.err != nil {
+		// The lexer failed, the parser only saw the end of the expression.
+		return nil, p.err
+	} else if err != nil {
+		return nil, err
+	}
+
+	if p.tok.kind != tokEOF {
+		return nil, p.errorf("unexpected %s", p.tok)
+	}
+
+	if n.typ != typeBool {
+		return nil, fmt.Errorf("expression is a %s, not a bool", n.typ)
+	}
+
+	return &Expr{src: src, eval: n.eval, details: p.details}, nil
+}
+
+// String returns the source of the expression.
+func (e *Expr) String() string {
+	return e.src
+}
+
+// NeedsDetails reports whether the expression uses a field the trending page doesn't show,
+// i.e. forks, open_issues, size, archived, fork or topics,
+// which are zero unless the repo is fetched from the GitHub API.
+func (e *Expr) NeedsDetails() bool {
+	return e.details
+}
+
+// Match reports whether the repo satisfies the expression.
+func (e *Expr) Match(repo *github.RepoTrending) bool {
+	return e.eval(&env{repo: repo}).(bool)
+}
+
+// valueType is the static type of an expression.
+type valueType int
+
+const (
+	typeBool valueType = iota
+	typeInt
+	typeString
+	typeList
+)
+
+func (t valueType) String() string {
+	return [...]string{"bool", "int", "string", "list"}[t]
+}
+
+// env is what an expression is evaluated against.
+type env struct {
+	repo *github.RepoTrending
+	// elem is the current element of the list inside any and all.
+	elem string
+}
+
+type evalFunc func(*env) interface{}
+
+// node is a type checked part of an expression.
+type node struct {
+	typ  valueType
+	eval evalFunc
+	// literal tells whether the node is a constant written in the expression.
+	literal bool
+}
+
+// field is a field of the repo available to the expressions.
+type field struct {
+	typ   valueType
+	value func(*github.RepoTrending) interface{}
+	// details tells whether the field is missing from the trending page.
+	details bool
+}
+
+var fields = map[string]field{
+	"name":        {typeString, func(r *github.RepoTrending) interface{} { return r.FullName }, false},
+	"owner":       {typeString, func(r *github.RepoTrending) interface{} { return r.Owner.Login }, false},
+	"description": {typeString, func(r *github.RepoTrending) interface{} { return r.Description }, false},
+	"language":    {typeString, func(r *github.RepoTrending) interface{} { return r.Language }, false},
+	"url":         {typeString, func(r *github.RepoTrending) interface{} { return r.HtmlURL }, false},
+	"stars":       {typeInt, func(r *github.RepoTrending) interface{} { return r.StargazersCount }, false},
+	"stars_today": {typeInt, func(r *github.RepoTrending) interface{} { return r.StarsToday }, false},
+	"forks":       {typeInt, func(r *github.RepoTrending) interface{} { return r.ForksCount }, true},
+	"open_issues": {typeInt, func(r *github.RepoTrending) interface{} { return r.OpenIssuesCount }, true},
+	"size":        {typeInt, func(r *github.RepoTrending) interface{} { return r.Size }, true},
+	"archived":    {typeBool, func(r *github.RepoTrending) interface{} { return r.Archived }, true},
+	"fork":        {typeBool, func(r *github.RepoTrending) interface{} { return r.Fork }, true},
+	"topics":      {typeList, func(r *github.RepoTrending) interface{} { return r.Topics }, true},
+}
+
+// fieldNames returns the names of the fields, sorted.
+func fieldNames() string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return strings.Join(names, ", ")
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokInt
+	tokString
+	tokIdent
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	if t.kind == tokEOF {
+		return "end of expression"
+	}
+
+	return fmt.Sprintf("%q at position %d", t.text, t.pos)
+}
+
+// lexer splits an expression into tokens.
+type lexer struct {
+	src string
+	pos int
+}
+
+// operators are sorted so the longest ones are matched first.
+var operators = []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "(", ")", ",", "#"}
+
+func (l *lexer) next() (token, error) {
+	for l.pos < len(l.src) && unicode.IsSpace(rune(l.src[l.pos])) {
+		l.pos++
+	}
+
+	start := l.pos
+	if l.pos >= len(l.src) {
+		return token{kind: tokEOF, pos: start}, nil
+	}
+
+	c := l.src[l.pos]
+
+	switch {
+	case c >= '0' && c <= '9':
+		for l.pos < len(l.src) && l.src[l.pos] >= '0' && l.src[l.pos] <= '9' {
+			l.pos++
+		}
+		return token{kind: tokInt, text: l.src[start:l.pos], pos: start}, nil
+	case c == '"':
+		l.pos++
+		for l.pos < len(l.src) && l.src[l.pos] != '"' {
+			if l.src[l.pos] == '\\' {
+				l.pos++
+			}
+			l.pos++
+		}
+		if l.pos >= len(l.src) {
+			return token{}, fmt.Errorf("unterminated string at position %d", start)
+		}
+		l.pos++
+
+		text, err := strconv.Unquote(l.src[start:l.pos])
+		if err != nil {
+			return token{}, fmt.Errorf("invalid string at position %d: %w", start, err)
+		}
+		return token{kind: tokString, text: text, pos: start}, nil
+	case c == '_' || unicode.IsLetter(rune(c)):
+		for l.pos < len(l.src) && (l.src[l.pos] == '_' || unicode.IsLetter(rune(l.src[l.pos])) || unicode.IsDigit(rune(l.src[l.pos]))) {
+			l.pos++
+		}
+		return token{kind: tokIdent, text: l.src[start:l.pos], pos: start}, nil
+	}
+
+	for _, op := range operators {
+		if strings.HasPrefix(l.src[l.pos:], op) {
+			l.pos += len(op)
+			return token{kind: tokOp, text: op, pos: start}, nil
+		}
+	}
+
+	return token{}, fmt.Errorf("unexpected %q at position %d", c, start)
+}
+
+// parser builds the type checked nodes of an expression by recursive descent.
+type parser struct {
+	lex lexer
+	tok token
+	err error
+	// inList tells whether # can be used, inside any and all.
+	inList bool
+	// details tells whether a field missing from the trending page was parsed.
+	details bool
+}
+
+func (p *parser) next() {
+	if p.err != nil {
+		return
+	}
+
+	p.tok, p.err = p.lex.next()
+	if p.err != nil {
+		p.tok = token{kind: tokEOF, pos: p.lex.pos}
+	}
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	if p.err != nil {
+		return p.err
+	}
+
+	return fmt.Errorf(format, args...)
+}
+
+func (p *parser) isOp(op string) bool {
+	return p.tok.kind == tokOp && p.tok.text == op
+}
+
+func (p *parser) expect(op string) error {
+	if !p.isOp(op) {
+		return p.errorf("expected %q, found %s", op, p.tok)
+	}
+
+	p.next()
+
+	return nil
+}
+
+// parseOr parses a || b.
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return node{}, err
+	}
+
+	for p.isOp("||") {
+		tok := p.tok
+		p.next()
+
+		right, err := p.parseAnd()
+		if err != nil {
+			return node{}, err
+		}
+
+		if left.typ != typeBool || right.typ != typeBool {
+			return node{}, fmt.Errorf("|| at position %d needs bools, found %s and %s", tok.pos, left.typ, right.typ)
+		}
+
+		l, r := left.eval, right.eval
+		left = node{typ: typeBool, eval: func(e *env) interface{} { return l(e).(bool) || r(e).(bool) }}
+	}
+
+	return left, nil
+}
+
+// parseAnd parses a && b.
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return node{}, err
+	}
+
+	for p.isOp("&&") {
+		tok := p.tok
+		p.next()
+
+		right, err := p.parseNot()
+		if err != nil {
+			return node{}, err
+		}
+
+		if left.typ != typeBool || right.typ != typeBool {
+			return node{}, fmt.Errorf("&& at position %d needs bools, found %s and %s", tok.pos, left.typ, right.typ)
+		}
+
+		l, r := left.eval, right.eval
+		left = node{typ: typeBool, eval: func(e *env) interface{} { return l(e).(bool) && r(e).(bool) }}
+	}
+
+	return left, nil
+}
+
+// parseNot parses !a.
+func (p *parser) parseNot() (node, error) {
+	if !p.isOp("!") {
+		return p.parseComparison()
+	}
+
+	tok := p.tok
+	p.next()
+
+	operand, err := p.parseNot()
+	if err != nil {
+		return node{}, err
+	}
+
+	if operand.typ != typeBool {
+		return node{}, fmt.Errorf("! at position %d needs a bool, found %s", tok.pos, operand.typ)
+	}
+
+	eval := operand.eval
+
+	return node{typ: typeBool, eval: func(e *env) interface{} { return !eval(e).(bool) }}, nil
+}
+
+// comparisons are the operators of compare.
+var comparisons = map[string]bool{"==": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true}
+
+// parseComparison parses a == b, a < b, a contains b, a in b and so on.
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return node{}, err
+	}
+
+	tok := p.tok
+
+	switch {
+	case tok.kind == tokOp && comparisons[tok.text]:
+		p.next()
+
+		right, err := p.parsePrimary()
+		if err != nil {
+			return node{}, err
+		}
+
+		return compare(tok, left, right)
+	case tok.kind == tokIdent && (tok.text == "contains" || tok.text == "startsWith" || tok.text == "endsWith" || tok.text == "matches" || tok.text == "in"):
+		p.next()
+
+		right, err := p.parsePrimary()
+		if err != nil {
+			return node{}, err
+		}
+
+		return stringOp(tok, left, right)
+	}
+
+	return left, nil
+}
+
+// compare builds a comparison of two values of the same type.
+func compare(tok token, left, right node) (node, error) {
+	if left.typ != right.typ {
+		return node{}, fmt.Errorf("%s compares a %s with a %s", tok, left.typ, right.typ)
+	}
+
+	l, r := left.eval, right.eval
+
+	switch tok.text {
+	case "==":
+		if left.typ == typeList {
+			return node{}, fmt.Errorf("%s can't compare lists", tok)
+		}
+		return node{typ: typeBool, eval: func(e *env) interface{} { return l(e) == r(e) }}, nil
+	case "!=":
+		if left.typ == typeList {
+			return node{}, fmt.Errorf("%s can't compare lists", tok)
+		}
+		return node{typ: typeBool, eval: func(e *env) interface{} { return l(e) != r(e) }}, nil
+	}
+
+	var less func(a, b interface{}) int
+	switch left.typ {
+	case typeInt:
+		less = func(a, b interface{}) int { return a.(int) - b.(int) }
+	case typeString:
+		less = func(a, b interface{}) int { return strings.Compare(a.(string), b.(string)) }
+	default:
+		return node{}, fmt.Errorf("%s can't order %ss", tok, left.typ)
+	}
+
+	op := tok.text
+
+	return node{typ: typeBool, eval: func(e *env) interface{} {
+		c := less(l(e), r(e))
+		switch op {
+		case "<":
+			return c < 0
+		case "<=":
+			return c <= 0
+		case ">":
+			return c > 0
+		}
+		return c >= 0
+	}}, nil
+}
+
+// stringOp builds the string and list operators.
+func stringOp(tok token, left, right node) (node, error) {
+	l, r := left.eval, right.eval
+
+	if tok.text == "in" {
+		if left.typ != typeString || right.typ != typeList {
+			return node{}, fmt.Errorf("%s needs a string and a list, found %s and %s", tok, left.typ, right.typ)
+		}
+
+		return node{typ: typeBool, eval: func(e *env) interface{} {
+			v := l(e).(string)
+			for _, item := range r(e).([]string) {
+				if item == v {
+					return true
+				}
+			}
+			return false
+		}}, nil
+	}
+
+	if left.typ != typeString || right.typ != typeString {
+		return node{}, fmt.Errorf("%s needs strings, found %s and %s", tok, left.typ, right.typ)
+	}
+
+	switch tok.text {
+	case "contains":
+		return node{typ: typeBool, eval: func(e *env) interface{} { return strings.Contains(l(e).(string), r(e).(string)) }}, nil
+	case "startsWith":
+		return node{typ: typeBool, eval: func(e *env) interface{} { return strings.HasPrefix(l(e).(string), r(e).(string)) }}, nil
+	case "endsWith":
+		return node{typ: typeBool, eval: func(e *env) interface{} { return strings.HasSuffix(l(e).(string), r(e).(string)) }}, nil
+	}
+
+	// matches takes a literal regular expression, so it's compiled once and checked now.
+	if !right.literal {
+		return node{}, fmt.Errorf("%s needs a literal regular expression", tok)
+	}
+
+	re, err := regexp.Compile(r(nil).(string))
+	if err != nil {
+		return node{}, fmt.Errorf("%s: %w", tok, err)
+	}
+
+	return node{typ: typeBool, eval: func(e *env) interface{} { return re.MatchString(l(e).(string)) }}, nil
+}
+
+// parsePrimary parses literals, fields, #, function calls and parentheses.
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.tok
+
+	switch tok.kind {
+	case tokInt:
+		p.next()
+
+		n, err := strconv.Atoi(tok.text)
+		if err != nil {
+			return node{}, fmt.Errorf("invalid number %s", tok)
+		}
+
+		return node{typ: typeInt, eval: func(*env) interface{} { return n }}, nil
+	case tokString:
+		p.next()
+		return node{typ: typeString, eval: func(*env) interface{} { return tok.text }, literal: true}, nil
+	case tokOp:
+		switch tok.text {
+		case "(":
+			p.next()
+
+			n, err := p.parseOr()
+			if err != nil {
+				return node{}, err
+			}
+
+			return n, p.expect(")")
+		case "#":
+			if !p.inList {
+				return node{}, fmt.Errorf("%s can only be used inside any or all", tok)
+			}
+
+			p.next()
+
+			return node{typ: typeString, eval: func(e *env) interface{} { return e.elem }}, nil
+		}
+	case tokIdent:
+		p.next()
+
+		switch tok.text {
+		case "true", "false":
+			v := tok.text == "true"
+			return node{typ: typeBool, eval: func(*env) interface{} { return v }}, nil
+		}
+
+		if p.isOp("(") {
+			return p.parseCall(tok)
+		}
+
+		f, ok := fields[tok.text]
+		if !ok {
+			return node{}, fmt.Errorf("unknown field %s, the fields are %s", tok, fieldNames())
+		}
+
+		p.details = p.details || f.details
+
+		return node{typ: f.typ, eval: func(e *env) interface{} { return f.value(e.repo) }}, nil
+	}
+
+	return node{}, p.errorf("unexpected %s", tok)
+}
+
+// parseCall parses the functions: any, all, len and lower.
+func (p *parser) parseCall(name token) (node, error) {
+	p.next()
+
+	switch name.text {
+	case "any", "all":
+		list, err := p.parseOr()
+		if err != nil {
+			return node{}, err
+		}
+
+		if list.typ != typeList {
+			return node{}, fmt.Errorf("%s needs a list, found %s", name, list.typ)
+		}
+
+		if err := p.expect(","); err != nil {
+			return node{}, err
+		}
+
+		inList := p.inList
+		p.inList = true
+		pred, err := p.parseOr()
+		p.inList = inList
+		if err != nil {
+			return node{}, err
+		}
+
+		if pred.typ != typeBool {
+			return node{}, fmt.Errorf("%s needs a bool predicate, found %s", name, pred.typ)
+		}
+
+		if err := p.expect(")"); err != nil {
+			return node{}, err
+		}
+
+		all := name.text == "all"
+		items, match := list.eval, pred.eval
+
+		return node{typ: typeBool, eval: func(e *env) interface{} {
+			inner := &env{repo: e.repo}
+			for _, item := range items(e).([]string) {
+				inner.elem = item
+				if match(inner).(bool) != all {
+					return !all
+				}
+			}
+			return all
+		}}, nil
+	case "len", "lower":
+		arg, err := p.parseOr()
+		if err != nil {
+			return node{}, err
+		}
+
+		if err := p.expect(")"); err != nil {
+			return node{}, err
+		}
+
+		eval := arg.eval
+
+		if name.text == "lower" {
+			if arg.typ != typeString {
+				return node{}, fmt.Errorf("%s needs a string, found %s", name, arg.typ)
+			}
+
+			return node{typ: typeString, eval: func(e *env) interface{} { return strings.ToLower(eval(e).(string)) }}, nil
+		}
+
+		switch arg.typ {
+		case typeString:
+			return node{typ: typeInt, eval: func(e *env) interface{} { return len([]rune(eval(e).(string))) }}, nil
+		case typeList:
+			return node{typ: typeInt, eval: func(e *env) interface{} { return len(eval(e).([]string)) }}, nil
+		}
+
+		return node{}, fmt.Errorf("%s needs a string or a list, found %s", name, arg.typ)
+	}
+
+	return node{}, fmt.Errorf("unknown function %s, the functions are any, all, len and lower", name)
+}
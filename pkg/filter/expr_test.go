@@ -0,0 +1,128 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/Arturomtz8/github-inspector/pkg/github"
+)
+
+func TestExpr(t *testing.T) {
+	repo := &github.RepoTrending{
+		FullName:        "foo/bar",
+		Owner:           github.Owner{Login: "foo"},
+		Description:     "A fast CLI for databases",
+		Language:        "Go",
+		StargazersCount: 500,
+		StarsToday:      42,
+		Topics:          []string{"cli", "database"},
+	}
+
+	var tests = []struct {
+		name  string
+		expr  string
+		match bool
+	}{
+		{name: "example", expr: `stars > 300 && language == "Go" && !archived && any(topics, # == "cli")`, match: true},
+		{name: "or", expr: `stars > 1000 || stars_today >= 42`, match: true},
+		{name: "precedence", expr: `false && true || true`, match: true},
+		{name: "parentheses", expr: `false && (true || true)`, match: false},
+		{name: "double negation", expr: `!!fork == false`, match: true},
+		{name: "not equal", expr: `owner != "foo"`, match: false},
+		{name: "string ordering", expr: `name < "goo/bar"`, match: true},
+		{name: "contains", expr: `lower(description) contains "cli"`, match: true},
+		{name: "starts with", expr: `name startsWith "foo/"`, match: true},
+		{name: "ends with", expr: `name endsWith "/baz"`, match: false},
+		{name: "matches", expr: `description matches "(?i)database"`, match: true},
+		{name: "in", expr: `"database" in topics`, match: true},
+		{name: "all", expr: `all(topics, len(#) > 2)`, match: true},
+		{name: "all failing", expr: `all(topics, # != "cli")`, match: false},
+		{name: "any on an empty list", expr: `any(topics, # == "crypto")`, match: false},
+		{name: "len", expr: `len(topics) == 2 && len(owner) == 3`, match: true},
+		{name: "escaped string", expr: `description != "say \"hi\""`, match: true},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			e, err := Compile(tc.expr)
+			require.NoError(t, err)
+			require.Equal(t, tc.match, e.Match(repo))
+		})
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	var tests = []struct {
+		name string
+		expr string
+		err  string
+	}{
+		{name: "unknown field", expr: `starz > 300`, err: `unknown field "starz" at position 0, the fields are archived, description`},
+		{name: "unknown function", expr: `upper(name) == "FOO"`, err: `unknown function "upper"`},
+		{name: "not a bool", expr: `stars`, err: "expression is a int, not a bool"},
+		{name: "mismatched types", expr: `stars > "300"`, err: `">" at position 6 compares a int with a string`},
+		{name: "not on an int", expr: `!stars`, err: "! at position 0 needs a bool, found int"},
+		{name: "hash outside a list", expr: `# == "cli"`, err: `"#" at position 0 can only be used inside any or all`},
+		{name: "any on a string", expr: `any(name, # == "x")`, err: `"any" at position 0 needs a list, found string`},
+		{name: "invalid regexp", expr: `name matches "("`, err: "missing closing )"},
+		{name: "regexp from a field", expr: `name matches description`, err: "needs a literal regular expression"},
+		{name: "unterminated string", expr: `name == "foo`, err: "unterminated string at position 8"},
+		{name: "missing parenthesis", expr: `(stars > 1`, err: `expected ")", found end of expression`},
+		{name: "trailing tokens", expr: `fork fork`, err: `unexpected "fork" at position 5`},
+		{name: "unexpected character", expr: `stars > 1 & fork`, err: `unexpected '&' at position 10`},
+		{name: "empty", expr: ``, err: "unexpected end of expression"},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Compile(tc.expr)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tc.err)
+		})
+	}
+}
+
+func TestPolicyWhere(t *testing.T) {
+	p := &Policy{Allow: []string{"golang"}, Where: `stars > 300`}
+	require.NoError(t, p.Compile())
+
+	ok, reason := p.Check(&github.RepoTrending{FullName: "foo/bar", StargazersCount: 10})
+	require.False(t, ok)
+	require.Equal(t, `doesn't satisfy "stars > 300"`, reason)
+
+	// allowed repos don't need to satisfy the expression.
+	ok, _ = p.Check(&github.RepoTrending{FullName: "golang/go", StargazersCount: 10})
+	require.True(t, ok)
+
+	// the expression given in the CLI is added to the one of the file.
+	require.NoError(t, p.And(`language == "Go"`))
+	ok, reason = p.Check(&github.RepoTrending{FullName: "foo/bar", StargazersCount: 500, Language: "Rust"})
+	require.False(t, ok)
+	require.Equal(t, `doesn't satisfy "(stars > 300) && (language == \"Go\")"`, reason)
+
+	require.Error(t, p.And(`language = "Go"`))
+}
+
+func TestNeedsDetailsWhere(t *testing.T) {
+	for expr, details := range map[string]bool{
+		`stars > 300 && language == "Go"`:          false,
+		`lower(description) contains "cli"`:        false,
+		`stars > 300 && !archived`:                 true,
+		`any(topics, # == "cli")`:                  true,
+		`name == "foo/bar" || forks > 10`:          true,
+		`open_issues < 100 && size < 1000 || fork`: true,
+	} {
+		e, err := Compile(expr)
+		require.NoError(t, err)
+		require.Equal(t, details, e.NeedsDetails(), expr)
+	}
+
+	// the expression given in the CLI makes the policy fetch the details.
+	p := &Policy{Where: `stars > 300`}
+	require.False(t, p.NeedsDetails())
+	require.NoError(t, p.And(`!archived`))
+	require.True(t, p.NeedsDetails())
+}
@@ -0,0 +1,235 @@
+// Package filter decides which trending repos are worth publishing,
+// keeping spam and scam repos out of the feed.
+package filter
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/Arturomtz8/github-inspector/pkg/github"
+)
+
+// Policy holds the rules a repo has to pass to be published.
+// The zero value lets every repo through.
+type Policy struct {
+	// Allow lists the owners ("owner") and repos ("owner/name") published whatever the other rules say.
+	Allow []string `json:"allow"`
+	// Block lists the owners and repos never published.
+	Block []string `json:"block"`
+	// Keywords block the repos whose description contains any of them, ignoring case.
+	Keywords []string `json:"keywords"`
+	// Patterns are regular expressions blocking the repos whose description matches any of them.
+	Patterns []string `json:"patterns"`
+	// MinStars is the minimum number of stars of a repo.
+	MinStars int `json:"min_stars"`
+	// MinStarsToday is the minimum number of stars gained during the trending period.
+	MinStarsToday int `json:"min_stars_today"`
+	// ExcludeArchived filters the archived repos out.
+	ExcludeArchived bool `json:"exclude_archived"`
+	// ExcludeForks filters the forks out.
+	ExcludeForks bool `json:"exclude_forks"`
+	// RequireDescription filters the repos without a description out.
+	RequireDescription bool `json:"require_description"`
+	// Where is an expression the repos have to satisfy, see Expr.
+	Where string `json:"where"`
+
+	compiled bool
+	patterns []*regexp.Regexp
+	where    *Expr
+}
+
+// Rejection is a repo filtered out and the reason why.
+type Rejection struct {
+	Repo   *github.RepoTrending
+	Reason string
+}
+
+// Load reads the policy from a JSON file.
+func Load(path string) (*Policy, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var p Policy
+	if err := json.Unmarshal(b, &p); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	if err := p.Compile(); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	return &p, nil
+}
+
+// Compile parses the patterns and the where expression of the policy,
+// it has to be called again after changing them.
+func (p *Policy) Compile() error {
+	patterns := make([]*regexp.Regexp, len(p.Patterns))
+
+	for i, pattern := range p.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return err
+		}
+
+		patterns[i] = re
+	}
+
+	var where *Expr
+	if strings.TrimSpace(p.Where) != "" {
+		var err error
+		if where, err = Compile(p.Where); err != nil {
+			return fmt.Errorf("invalid where expression: %w", err)
+		}
+	}
+
+	p.patterns, p.where, p.compiled = patterns, where, true
+
+	return nil
+}
+
+// And adds an expression the repos have to satisfy, along with the current one.
+func (p *Policy) And(where string) error {
+	if strings.TrimSpace(p.Where) == "" {
+		p.Where = where
+	} else {
+		p.Where = "(" + p.Where + ") && (" + where + ")"
+	}
+
+	return p.Compile()
+}
+
+// Allowing returns a copy of the policy that also allows the given owners and repos.
+// It works on a nil receiver, returning nil.
+func (p *Policy) Allowing(allow ...string) *Policy {
+	if p == nil || len(allow) == 0 {
+		return p
+	}
+
+	cp := *p
+	cp.Allow = append(append([]string(nil), p.Allow...), allow...)
+
+	return &cp
+}
+
+// NeedsDetails reports whether the policy checks fields the trending page doesn't show,
+// like archived and fork, which have to be fetched from the GitHub API before filtering.
+// It works on a nil receiver, returning false.
+func (p *Policy) NeedsDetails() bool {
+	if p == nil {
+		return false
+	}
+
+	if !p.compiled {
+		if err := p.Compile(); err != nil {
+			// Check rejects every repo anyway.
+			return false
+		}
+	}
+
+	return p.ExcludeArchived || p.ExcludeForks || (p.where != nil && p.where.NeedsDetails())
+}
+
+// Allows reports whether the repo is published whatever the other rules say.
+// It works on a nil receiver, returning false.
+func (p *Policy) Allows(fullName string) bool {
+	return p != nil && matches(p.Allow, fullName)
+}
+
+// Check reports whether the repo passes the policy,
+// and the reason why if it doesn't.
+func (p *Policy) Check(repo *github.RepoTrending) (bool, string) {
+	if !p.compiled {
+		if err := p.Compile(); err != nil {
+			return false, err.Error()
+		}
+	}
+
+	if matches(p.Allow, repo.FullName) {
+		return true, ""
+	}
+
+	if matches(p.Block, repo.FullName) {
+		return false, "blocked"
+	}
+
+	if p.ExcludeArchived && repo.Archived {
+		return false, "archived"
+	}
+
+	if p.ExcludeForks && repo.Fork {
+		return false, "fork"
+	}
+
+	description := strings.TrimSpace(repo.Description)
+	if p.RequireDescription && description == "" {
+		return false, "no description"
+	}
+
+	if repo.StargazersCount < p.MinStars {
+		return false, fmt.Sprintf("%d stars, less than %d", repo.StargazersCount, p.MinStars)
+	}
+
+	if repo.StarsToday < p.MinStarsToday {
+		return false, fmt.Sprintf("%d stars today, less than %d", repo.StarsToday, p.MinStarsToday)
+	}
+
+	lower := strings.ToLower(description)
+	for _, keyword := range p.Keywords {
+		if keyword != "" && strings.Contains(lower, strings.ToLower(keyword)) {
+			return false, fmt.Sprintf("description contains %q", keyword)
+		}
+	}
+
+	for _, re := range p.patterns {
+		if re.MatchString(description) {
+			return false, fmt.Sprintf("description matches %q", re.String())
+		}
+	}
+
+	if p.where != nil && !p.where.Match(repo) {
+		return false, fmt.Sprintf("doesn't satisfy %q", p.where.String())
+	}
+
+	return true, ""
+}
+
+// Apply returns the repos that pass the policy, and the ones filtered out along with the reason.
+// A nil policy lets every repo through.
+func (p *Policy) Apply(repos []*github.RepoTrending) ([]*github.RepoTrending, []Rejection) {
+	if p == nil {
+		return repos, nil
+	}
+
+	var kept []*github.RepoTrending
+	var rejected []Rejection
+
+	for _, repo := range repos {
+		if ok, reason := p.Check(repo); !ok {
+			rejected = append(rejected, Rejection{Repo: repo, Reason: reason})
+			continue
+		}
+
+		kept = append(kept, repo)
+	}
+
+	return kept, rejected
+}
+
+// matches reports whether the repo, or its owner, is in the list, ignoring case.
+func matches(list []string, fullName string) bool {
+	owner, _, _ := strings.Cut(fullName, "/")
+
+	for _, entry := range list {
+		if strings.EqualFold(entry, fullName) || strings.EqualFold(entry, owner) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,149 @@
+package filter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/Arturomtz8/github-inspector/pkg/github"
+)
+
+func TestCheck(t *testing.T) {
+	p := &Policy{
+		Allow:              []string{"golang/go"},
+		Block:              []string{"scammer", "foo/spam"},
+		Keywords:           []string{"airdrop"},
+		Patterns:           []string{`(?i)free\s+(btc|eth)`},
+		MinStars:           100,
+		MinStarsToday:      10,
+		ExcludeArchived:    true,
+		ExcludeForks:       true,
+		RequireDescription: true,
+	}
+
+	var tests = []struct {
+		name   string
+		repo   github.RepoTrending
+		ok     bool
+		reason string
+	}{
+		{
+			name: "good repo",
+			repo: github.RepoTrending{FullName: "foo/bar", Description: "a good project", StargazersCount: 500, StarsToday: 50},
+			ok:   true,
+		},
+		{
+			name: "allowed repo skips the other rules",
+			repo: github.RepoTrending{FullName: "golang/go", Archived: true},
+			ok:   true,
+		},
+		{
+			name:   "blocked owner",
+			repo:   github.RepoTrending{FullName: "Scammer/wallet", Description: "a wallet", StargazersCount: 500, StarsToday: 50},
+			reason: "blocked",
+		},
+		{
+			name:   "blocked repo",
+			repo:   github.RepoTrending{FullName: "foo/spam", Description: "a project", StargazersCount: 500, StarsToday: 50},
+			reason: "blocked",
+		},
+		{
+			name:   "archived",
+			repo:   github.RepoTrending{FullName: "foo/old", Description: "a project", Archived: true},
+			reason: "archived",
+		},
+		{
+			name:   "fork",
+			repo:   github.RepoTrending{FullName: "foo/fork", Description: "a project", Fork: true},
+			reason: "fork",
+		},
+		{
+			name:   "no description",
+			repo:   github.RepoTrending{FullName: "foo/bar", Description: "  ", StargazersCount: 500, StarsToday: 50},
+			reason: "no description",
+		},
+		{
+			name:   "not enough stars",
+			repo:   github.RepoTrending{FullName: "foo/bar", Description: "a project", StargazersCount: 50, StarsToday: 50},
+			reason: "50 stars, less than 100",
+		},
+		{
+			name:   "not enough stars today",
+			repo:   github.RepoTrending{FullName: "foo/bar", Description: "a project", StargazersCount: 500, StarsToday: 5},
+			reason: "5 stars today, less than 10",
+		},
+		{
+			name:   "keyword",
+			repo:   github.RepoTrending{FullName: "foo/bar", Description: "Claim your AIRDROP", StargazersCount: 500, StarsToday: 50},
+			reason: `description contains "airdrop"`,
+		},
+		{
+			name:   "pattern",
+			repo:   github.RepoTrending{FullName: "foo/bar", Description: "Get FREE  ETH now", StargazersCount: 500, StarsToday: 50},
+			reason: `description matches "(?i)free\\s+(btc|eth)"`,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ok, reason := p.Check(&tc.repo)
+			require.Equal(t, tc.ok, ok)
+			require.Equal(t, tc.reason, reason)
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	repos := []*github.RepoTrending{
+		{FullName: "foo/bar", Description: "a good project"},
+		{FullName: "foo/spam"},
+	}
+
+	// a nil policy lets every repo through.
+	var p *Policy
+	kept, rejected := p.Apply(repos)
+	require.Len(t, kept, 2)
+	require.Empty(t, rejected)
+
+	p = &Policy{Block: []string{"foo/spam"}}
+	kept, rejected = p.Apply(repos)
+	require.Equal(t, []*github.RepoTrending{repos[0]}, kept)
+	require.Equal(t, []Rejection{{Repo: repos[1], Reason: "blocked"}}, rejected)
+
+	// allowing a repo doesn't change the original policy.
+	kept, rejected = p.Allowing("foo/spam").Apply(repos)
+	require.Len(t, kept, 2)
+	require.Empty(t, rejected)
+	require.Empty(t, p.Allow)
+}
+
+func TestNeedsDetails(t *testing.T) {
+	var p *Policy
+	require.False(t, p.NeedsDetails())
+	require.False(t, (&Policy{MinStars: 10, RequireDescription: true}).NeedsDetails())
+	require.True(t, (&Policy{ExcludeArchived: true}).NeedsDetails())
+	require.True(t, (&Policy{ExcludeForks: true}).NeedsDetails())
+
+	require.False(t, p.Allows("foo/bar"))
+	require.True(t, (&Policy{Allow: []string{"foo"}}).Allows("foo/bar"))
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "filter.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"block": ["foo"], "patterns": ["(?i)scam"], "min_stars": 10}`), 0o644))
+
+	p, err := Load(path)
+	require.NoError(t, err)
+	require.Equal(t, []string{"foo"}, p.Block)
+	require.Equal(t, 10, p.MinStars)
+
+	ok, _ := p.Check(&github.RepoTrending{FullName: "bar/baz", Description: "not a SCAM", StargazersCount: 20})
+	require.False(t, ok)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"patterns": ["("]}`), 0o644))
+	_, err = Load(path)
+	require.Error(t, err)
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"os"
 	"strconv"
 	"strings"
 	"time"
@@ -23,6 +24,8 @@ const (
 	TimeMonth = "monthly"
 	// Default GitHub URL.
 	RepoURL string = "https://api.github.com/search/repositories"
+	// ReposURL is the GitHub API URL of a single repository, followed by "/owner/name".
+	ReposURL string = "https://api.github.com/repos"
 	// Standard mode: github.com/trending
 	modeRepositories = "repositories"
 	// Base URL for the github website
@@ -58,10 +61,16 @@ type RepoTrending struct {
 	Size            int
 	Language        string
 	StargazersCount int `json:"stargazers_count"`
+	// StarsToday is the number of stars gained during the trending period,
+	// only known for the repos scraped from the trending page.
+	StarsToday      int `json:"stars_today,omitempty"`
 	ForksCount      int `json:"forks_count"`
+	Fork            bool
 	Archived        bool
 	OpenIssuesCount int `json:"open_issues_count"`
 	Topics          []string
+	// Comment is an editorial comment on the repo, it's never set by GitHub.
+	Comment string `json:"comment,omitempty"`
 }
 
 // SearchGithubTrending function returns a list treding repositores on GitHub.
@@ -72,7 +81,7 @@ func SearchGithubTrending(term, APIurl string) (*TrendingSearchResult, error) {
 	// q := url.QueryEscape(strings.Join(terms, " "))
 	term = url.QueryEscape(term)
 	// https://api.github.com/search/issues?q=stress+test+label:bug+language:python+state:closed&per_page=100
-	resp, err := http.Get(APIurl + "?q=stars:1000..15000+archived:false+language:" + term + "&per_page=5&sort=stars&order=desc")
+	resp, err := getAPI(APIurl + "?q=stars:1000..15000+archived:false+language:" + term + "&per_page=5&sort=stars&order=desc")
 	if err != nil {
 		return nil, err
 	}
@@ -109,7 +118,7 @@ func GetRepository(apiURL, name, lang, author string) (*RepoTrending, error) {
 
 	baseUrl.RawQuery = params
 
-	resp, err := http.Get(baseUrl.String())
+	resp, err := getAPI(baseUrl.String())
 	if err != nil {
 		return nil, err
 	}
@@ -132,6 +141,46 @@ func GetRepository(apiURL, name, lang, author string) (*RepoTrending, error) {
 	return nil, errors.New("project not found")
 }
 
+// GetRepositoryByName returns the repository with the given full name, e.g. "owner/name".
+func GetRepositoryByName(apiURL, fullName string) (*RepoTrending, error) {
+	owner, name, ok := strings.Cut(fullName, "/")
+	if !ok || owner == "" || name == "" {
+		return nil, fmt.Errorf("invalid repository name %q", fullName)
+	}
+
+	resp, err := getAPI(strings.TrimSuffix(apiURL, "/") + "/" + url.PathEscape(owner) + "/" + url.PathEscape(name))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("invalid request with status %s", resp.Status)
+	}
+
+	var repo RepoTrending
+	if err := json.NewDecoder(resp.Body).Decode(&repo); err != nil {
+		return nil, err
+	}
+
+	return &repo, nil
+}
+
+// getAPI sends a GET request to the GitHub API, authenticated with GITHUB_TOKEN if it's set,
+// which raises the rate limit from 60 requests per hour to 5000.
+func getAPI(u string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	return http.DefaultClient.Do(req)
+}
+
 // encodeQueryComponents returns encoded query params at once.
 func encodeQueryComponents(name, lang, author string) (string, error) {
 	params := url.Values{}
@@ -215,6 +264,9 @@ func GetTrendingRepos(time, language string) (*TrendingSearchResult, error) {
 		starsString = strings.Replace(starsString, ",", "", 1)
 		stars, _ := strconv.Atoi(starsString)
 
+		// e.g. "1,234 stars today".
+		starsToday := parseStars(s.Find("span.float-sm-right").Text())
+
 		p := &RepoTrending{
 			FullName: name,
 			Owner: Owner{
@@ -224,6 +276,7 @@ func GetTrendingRepos(time, language string) (*TrendingSearchResult, error) {
 			Description:     description,
 			Language:        language,
 			StargazersCount: stars,
+			StarsToday:      starsToday,
 		}
 
 		repos.Items = append(repos.Items, p)
@@ -232,6 +285,21 @@ func GetTrendingRepos(time, language string) (*TrendingSearchResult, error) {
 	return &repos, nil
 }
 
+// parseStars returns the first number of a text like "1,234 stars today",
+// 0 if there's none.
+func parseStars(text string) int {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return 0
+	}
+
+	// We can safely ignore the error,
+	// since we're ok with a zero values if somethings goes wrong.
+	stars, _ := strconv.Atoi(strings.ReplaceAll(fields[0], ",", ""))
+
+	return stars
+}
+
 // appendBaseHostToPath will add the base host to a relative url urlStr.
 // A urlStr like "/trending" will be returned as https://github.com/trending
 func appendBaseHostToPath(baseurl, urlStr string, exists bool) *url.URL {
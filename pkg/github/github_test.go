@@ -244,3 +244,73 @@ func TestGetRepository(t *testing.T) {
 		})
 	}
 }
+
+func TestParseStars(t *testing.T) {
+	var tests = []struct {
+		name  string
+		text  string
+		stars int
+	}{
+		{name: "stars today", text: "\n   1,234 stars today\n", stars: 1234},
+		{name: "stars this week", text: "87 stars this week", stars: 87},
+		{name: "missing", text: "", stars: 0},
+		{name: "not a number", text: "stars today", stars: 0},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.stars, parseStars(tc.text))
+		})
+	}
+}
+
+func TestGetRepositoryByName(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/danvergara/dblab" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.Write([]byte(`{
+  "full_name": "danvergara/dblab",
+  "html_url": "https://github.com/danvergara/dblab",
+  "description": "The database client every command line junkie deserves.",
+  "owner": {"login": "danvergara"},
+  "language": "Go",
+  "stargazers_count": 2345
+}`))
+	}))
+	defer s.Close()
+
+	repo, err := GetRepositoryByName(s.URL, "danvergara/dblab")
+	require.NoError(t, err)
+	require.Equal(t, "danvergara/dblab", repo.FullName)
+	require.Equal(t, "danvergara", repo.Owner.Login)
+	require.Equal(t, "Go", repo.Language)
+	require.Equal(t, 2345, repo.StargazersCount)
+
+	_, err = GetRepositoryByName(s.URL, "danvergara/missing")
+	require.Error(t, err)
+
+	_, err = GetRepositoryByName(s.URL, "dblab")
+	require.Error(t, err)
+}
+
+func TestGetAPIToken(t *testing.T) {
+	var auth string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+	}))
+	defer s.Close()
+
+	t.Setenv("GITHUB_TOKEN", "")
+	_, err := getAPI(s.URL)
+	require.NoError(t, err)
+	require.Empty(t, auth)
+
+	t.Setenv("GITHUB_TOKEN", "secret")
+	_, err = getAPI(s.URL)
+	require.NoError(t, err)
+	require.Equal(t, "Bearer secret", auth)
+}
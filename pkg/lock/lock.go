@@ -0,0 +1,232 @@
+// Package lock provides a lease lock held in Redis,
+// so a single instance of the bot does a given work at a time.
+package lock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	// ErrHeld is returned when another instance holds the lock.
+	ErrHeld = errors.New("another run holds the lock")
+	// ErrLost is returned when the lock was taken over by another instance,
+	// after the lease expired.
+	ErrLost = errors.New("the lock was lost")
+)
+
+// acquireScript takes the lock and returns a fencing token,
+// greater than the tokens of every previous holder. It returns 0 if the lock is held.
+var acquireScript = redis.NewScript(`
+if redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+	return redis.call("INCR", KEYS[2])
+end
+return 0
+`)
+
+// fenceCheck stops a script, returning 0, unless the lock is still held by the given token
+// and no other instance acquired it since, i.e. the fencing token is the last one.
+const fenceCheck = `
+if redis.call("GET", KEYS[1]) ~= ARGV[1] or redis.call("GET", KEYS[2]) ~= ARGV[2] then
+	return 0
+end
+`
+
+// checkScript reports whether the lock is still held with the fencing token.
+var checkScript = NewFencedScript(`return 1`)
+
+// NewFencedScript returns a script running src only while the lock is still held
+// with the fencing token of the acquisition, see RunFenced.
+// src gets the keys and arguments given to RunFenced from KEYS[3] and ARGV[3] on,
+// the first ones are the lock's, and it has to return a value other than 0.
+func NewFencedScript(src string) *redis.Script {
+	return redis.NewScript(fenceCheck + src)
+}
+
+// refreshScript extends the lease only if the lock is still held by the given token.
+var refreshScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// releaseScript deletes the lock only if it's still held by the given token,
+// so an instance never releases a lock acquired by another one after its lease expired.
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// Lock is a lease lock on a Redis key. The lease expires on its own
+// if the holder dies, so the lock never stays stuck.
+//
+// Every acquisition gets a fencing token, a number increasing with every holder.
+// A holder that was paused past its lease can tell with Check
+// that another one took over, before doing any more work.
+type Lock struct {
+	rdb   *redis.Client
+	key   string
+	token string
+	lease time.Duration
+	fence int64
+}
+
+// New returns a lock on the given key, held for lease at a time.
+func New(rdb *redis.Client, key string, lease time.Duration) *Lock {
+	b := make([]byte, 16)
+	// We can safely ignore the error, crypto/rand never fails on supported platforms.
+	rand.Read(b)
+
+	return &Lock{rdb: rdb, key: key, token: hex.EncodeToString(b), lease: lease}
+}
+
+// fenceKey is the key of the counter giving the fencing tokens.
+func (l *Lock) fenceKey() string {
+	return l.key + ":fence"
+}
+
+// Acquire takes the lock, it reports false if another instance holds it.
+func (l *Lock) Acquire(ctx context.Context) (bool, error) {
+	fence, err := acquireScript.Run(ctx, l.rdb, []string{l.key, l.fenceKey()}, l.token, l.lease.Milliseconds()).Int64()
+	if err != nil {
+		return false, err
+	}
+
+	l.fence = fence
+
+	return fence != 0, nil
+}
+
+// Wait takes the lock, retrying until it's acquired or timeout is over.
+// It returns ErrHeld if another instance still holds the lock then.
+func (l *Lock) Wait(ctx context.Context, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		ok, err := l.Acquire(ctx)
+		if err != nil {
+			return err
+		}
+
+		if ok {
+			return nil
+		}
+
+		if !time.Now().Before(deadline) {
+			return ErrHeld
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// Fence returns the fencing token of the last acquisition, 0 if the lock was never acquired.
+func (l *Lock) Fence() int64 {
+	return l.fence
+}
+
+// TTL returns the time left on the lease of the current holder, 0 if the lock is free.
+func (l *Lock) TTL(ctx context.Context) (time.Duration, error) {
+	ttl, err := l.rdb.PTTL(ctx, l.key).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	// Redis replies -2 if the key doesn't exist.
+	if ttl < 0 {
+		return 0, nil
+	}
+
+	return ttl, nil
+}
+
+// Check returns ErrLost if the lock isn't held anymore with the fencing token of the last acquisition.
+func (l *Lock) Check(ctx context.Context) error {
+	return l.RunFenced(ctx, checkScript, nil)
+}
+
+// RunFenced runs a script returned by NewFencedScript, so its writes only go through
+// while the lock is held with the fencing token of the last acquisition.
+// It returns ErrLost otherwise, and the script wrote nothing.
+func (l *Lock) RunFenced(ctx context.Context, script *redis.Script, keys []string, args ...interface{}) error {
+	keys = append([]string{l.key, l.fenceKey()}, keys...)
+	args = append([]interface{}{l.token, l.fence}, args...)
+
+	n, err := script.Run(ctx, l.rdb, keys, args...).Int()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return ErrLost
+	}
+
+	return nil
+}
+
+// Refresh extends the lease, it reports false if the lock was lost.
+func (l *Lock) Refresh(ctx context.Context) (bool, error) {
+	n, err := refreshScript.Run(ctx, l.rdb, []string{l.key}, l.token, l.lease.Milliseconds()).Int()
+	if err != nil {
+		return false, err
+	}
+
+	return n == 1, nil
+}
+
+// Release drops the lock if it's still held.
+func (l *Lock) Release(ctx context.Context) error {
+	return releaseScript.Run(ctx, l.rdb, []string{l.key}, l.token).Err()
+}
+
+// Keep refreshes the lease every third of it until the returned context is done.
+// The context is canceled as soon as the lock is lost, or can't be refreshed
+// before the lease expires, so the work done under the lock stops.
+func (l *Lock) Keep(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	go func() {
+		ticker := time.NewTicker(l.lease / 3)
+		defer ticker.Stop()
+
+		expires := time.Now().Add(l.lease)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			ok, err := l.Refresh(ctx)
+			switch {
+			case ctx.Err() != nil:
+				return
+			case err != nil && time.Now().Before(expires):
+				// The next tick retries, the lease is still ours.
+				log.Printf("error occurred refreshing lock %s: %v", l.key, err)
+			case err != nil, !ok:
+				log.Printf("lock %s was lost", l.key)
+				cancel()
+				return
+			default:
+				expires = time.Now().Add(l.lease)
+			}
+		}
+	}()
+
+	return ctx, cancel
+}
@@ -0,0 +1,116 @@
+package lock
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/Arturomtz8/github-inspector/pkg/redisclient"
+)
+
+func TestLock(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping short mode")
+	}
+
+	ctx := context.Background()
+
+	rdb, err := redisclient.Connect(ctx, os.Getenv("REDIS_URI"))
+	require.NoError(t, err)
+	defer rdb.Close()
+
+	const key = "lock/test"
+	require.NoError(t, rdb.Del(ctx, key, key+":fence").Err())
+	defer rdb.Del(ctx, key, key+":fence")
+
+	first := New(rdb, key, time.Second)
+	second := New(rdb, key, time.Second)
+
+	ok, err := first.Acquire(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	require.NoError(t, first.Check(ctx))
+
+	ok, err = second.Acquire(ctx)
+	require.NoError(t, err)
+	require.False(t, ok, "the lock is held by the first instance")
+
+	require.ErrorIs(t, second.Wait(ctx, 0), ErrHeld)
+
+	ttl, err := second.TTL(ctx)
+	require.NoError(t, err)
+	require.Greater(t, ttl, time.Duration(0))
+
+	ok, err = second.Refresh(ctx)
+	require.NoError(t, err)
+	require.False(t, ok, "only the holder refreshes the lock")
+
+	// releasing a lock held by another instance does nothing.
+	require.NoError(t, second.Release(ctx))
+
+	ok, err = first.Refresh(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// the lease of the first instance expires, the second one takes over
+	// with a greater fencing token.
+	require.NoError(t, rdb.Del(ctx, key).Err())
+	require.NoError(t, second.Wait(ctx, time.Second))
+	require.Greater(t, second.Fence(), first.Fence())
+	require.ErrorIs(t, first.Check(ctx), ErrLost)
+	require.NoError(t, second.Check(ctx))
+
+	// releasing a lock taken over does nothing.
+	require.NoError(t, first.Release(ctx))
+	require.NoError(t, second.Check(ctx))
+
+	// the lock is lost once another instance takes it over.
+	kept, cancel := second.Keep(ctx)
+	defer cancel()
+
+	require.NoError(t, rdb.Set(ctx, key, "someone else", time.Second).Err())
+
+	select {
+	case <-kept.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("the context wasn't canceled when the lock was lost")
+	}
+}
+
+func TestRunFenced(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping short mode")
+	}
+
+	ctx := context.Background()
+
+	rdb, err := redisclient.Connect(ctx, os.Getenv("REDIS_URI"))
+	require.NoError(t, err)
+	defer rdb.Close()
+
+	const key, written = "lock/fenced", "lock/fenced/written"
+	require.NoError(t, rdb.Del(ctx, key, key+":fence", written).Err())
+	defer rdb.Del(ctx, key, key+":fence", written)
+
+	script := NewFencedScript(`return redis.call("INCRBY", KEYS[3], ARGV[3])`)
+
+	first := New(rdb, key, time.Minute)
+	require.NoError(t, first.Wait(ctx, 0))
+	require.NoError(t, first.RunFenced(ctx, script, []string{written}, 2))
+
+	// another instance takes the lock over, the writes of the first one don't go through.
+	require.NoError(t, rdb.Del(ctx, key).Err())
+	second := New(rdb, key, time.Minute)
+	require.NoError(t, second.Wait(ctx, 0))
+
+	require.ErrorIs(t, first.RunFenced(ctx, script, []string{written}, 3), ErrLost)
+	require.NoError(t, second.RunFenced(ctx, script, []string{written}, 5))
+
+	n, err := rdb.Get(ctx, written).Int()
+	require.NoError(t, err)
+	require.Equal(t, 7, n)
+}
@@ -0,0 +1,230 @@
+// Package mastodon publishes the trending repos as Mastodon statuses,
+// through the REST API of an instance.
+package mastodon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// statusesPath is the endpoint statuses are posted to.
+	statusesPath = "/api/v1/statuses"
+	// maxRetries is the number of times a status is posted again after hitting the rate limit.
+	maxRetries = 2
+	// defaultRetryAfter is the wait after hitting the rate limit of an instance that doesn't tell when it resets.
+	defaultRetryAfter = time.Minute
+)
+
+// Visibility tells who can see a status.
+type Visibility string
+
+const (
+	// Public statuses show up in the public timelines.
+	Public Visibility = "public"
+	// Unlisted statuses are public, but left out of the public timelines.
+	Unlisted Visibility = "unlisted"
+	// Private statuses are only shown to the followers.
+	Private Visibility = "private"
+	// Direct statuses are only shown to the mentioned users.
+	Direct Visibility = "direct"
+)
+
+// ParseVisibility returns the visibility named v, defaulting to Public if it's empty.
+func ParseVisibility(v string) (Visibility, error) {
+	switch vis := Visibility(strings.ToLower(v)); vis {
+	case "":
+		return Public, nil
+	case Public, Unlisted, Private, Direct:
+		return vis, nil
+	}
+
+	return "", fmt.Errorf("invalid visibility %q, expected public, unlisted, private or direct", v)
+}
+
+// Status is a status to be posted.
+type Status struct {
+	Text       string
+	Visibility Visibility
+	// Language is the ISO 639 code of the language of the status, e.g. "en".
+	Language string
+	// SpoilerText is the content warning shown instead of the status until it's expanded.
+	SpoilerText string
+}
+
+// PostedStatus is a status accepted by the instance.
+type PostedStatus struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
+}
+
+// apiError is the body of the errors of the API.
+type apiError struct {
+	Error string `json:"error"`
+}
+
+// rateLimit is the state of the rate limit of the account, as told by the last response.
+type rateLimit struct {
+	known     bool
+	remaining int
+	reset     time.Time
+}
+
+// Client posts statuses to a Mastodon instance.
+// It waits for the rate limit to reset once the instance says no request is left.
+type Client struct {
+	// Instance is the URL of the instance, e.g. https://fosstodon.org.
+	Instance string
+	// Token is the access token of the account, it needs the write:statuses scope.
+	Token string
+	// HTTP sends the requests. Defaults to http.DefaultClient.
+	HTTP *http.Client
+
+	mu    sync.Mutex
+	limit rateLimit
+}
+
+// NewClient returns a client of the instance, authenticated with the access token.
+func NewClient(instance, token string) *Client {
+	return &Client{Instance: strings.TrimSuffix(instance, "/"), Token: token}
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTP == nil {
+		return http.DefaultClient
+	}
+
+	return c.HTTP
+}
+
+// PostStatus posts the status. The idempotency key keeps the instance from posting
+// the same status twice when a request is retried.
+func (c *Client) PostStatus(ctx context.Context, s Status, idempotencyKey string) (*PostedStatus, error) {
+	form := url.Values{}
+	form.Set("status", s.Text)
+	if s.Visibility != "" {
+		form.Set("visibility", string(s.Visibility))
+	}
+	if s.Language != "" {
+		form.Set("language", s.Language)
+	}
+	if s.SpoilerText != "" {
+		form.Set("spoiler_text", s.SpoilerText)
+	}
+
+	for attempt := 0; ; attempt++ {
+		if err := c.waitLimit(ctx); err != nil {
+			return nil, err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.Instance+statusesPath, strings.NewReader(form.Encode()))
+		if err != nil {
+			return nil, err
+		}
+
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+		if idempotencyKey != "" {
+			req.Header.Set("Idempotency-Key", idempotencyKey)
+		}
+
+		resp, err := c.httpClient().Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		c.updateLimit(resp.Header)
+
+		if resp.StatusCode == http.StatusTooManyRequests && attempt < maxRetries {
+			resp.Body.Close()
+			c.exhaust()
+			continue
+		}
+
+		status, err := decodeStatus(resp)
+		resp.Body.Close()
+
+		return status, err
+	}
+}
+
+// decodeStatus returns the status of a response, or the error the instance replied with.
+func decodeStatus(resp *http.Response) (*PostedStatus, error) {
+	if resp.StatusCode != http.StatusOK {
+		var apiErr apiError
+		if err := json.NewDecoder(resp.Body).Decode(&apiErr); err == nil && apiErr.Error != "" {
+			return nil, fmt.Errorf("posting status: %s: %s", resp.Status, apiErr.Error)
+		}
+
+		return nil, fmt.Errorf("posting status: invalid request with status %s", resp.Status)
+	}
+
+	var status PostedStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, err
+	}
+
+	return &status, nil
+}
+
+// updateLimit keeps the rate limit told by the X-RateLimit headers of a response.
+func (c *Client) updateLimit(h http.Header) {
+	remaining, err := strconv.Atoi(h.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+
+	// Mastodon sends the time of the reset as an ISO 8601 timestamp.
+	reset, err := time.Parse(time.RFC3339Nano, h.Get("X-RateLimit-Reset"))
+	if err != nil {
+		reset = time.Now().Add(defaultRetryAfter)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.limit = rateLimit{known: true, remaining: remaining, reset: reset}
+}
+
+// exhaust records that no request is left, after the instance replied 429.
+func (c *Client) exhaust() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.limit.known || !c.limit.reset.After(time.Now()) {
+		c.limit.reset = time.Now().Add(defaultRetryAfter)
+	}
+
+	c.limit.known = true
+	c.limit.remaining = 0
+}
+
+// waitLimit waits for the rate limit to reset if no request is left.
+func (c *Client) waitLimit(ctx context.Context) error {
+	c.mu.Lock()
+	limit := c.limit
+	c.mu.Unlock()
+
+	if !limit.known || limit.remaining > 0 {
+		return nil
+	}
+
+	wait := time.Until(limit.reset)
+	if wait <= 0 {
+		return nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(wait):
+		return nil
+	}
+}
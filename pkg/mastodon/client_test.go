@@ -0,0 +1,108 @@
+package mastodon
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseVisibility(t *testing.T) {
+	v, err := ParseVisibility("")
+	require.NoError(t, err)
+	require.Equal(t, Public, v)
+
+	v, err = ParseVisibility("Unlisted")
+	require.NoError(t, err)
+	require.Equal(t, Unlisted, v)
+
+	_, err = ParseVisibility("followers")
+	require.Error(t, err)
+}
+
+func TestPostStatus(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, statusesPath, r.URL.Path)
+		require.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		require.Equal(t, "seen:mastodon:default:go:foo/bar:run", r.Header.Get("Idempotency-Key"))
+
+		require.NoError(t, r.ParseForm())
+		require.Equal(t, "foo/bar: a good project", r.PostForm.Get("status"))
+		require.Equal(t, "unlisted", r.PostForm.Get("visibility"))
+		require.Equal(t, "en", r.PostForm.Get("language"))
+		require.Equal(t, "trending repos", r.PostForm.Get("spoiler_text"))
+
+		w.Write([]byte(`{"id": "110", "url": "https://example.social/@bot/110"}`))
+	}))
+	defer s.Close()
+
+	c := NewClient(s.URL+"/", "token")
+
+	posted, err := c.PostStatus(context.Background(), Status{
+		Text:        "foo/bar: a good project",
+		Visibility:  Unlisted,
+		Language:    "en",
+		SpoilerText: "trending repos",
+	}, "seen:mastodon:default:go:foo/bar:run")
+	require.NoError(t, err)
+	require.Equal(t, "110", posted.ID)
+	require.Equal(t, "https://example.social/@bot/110", posted.URL)
+}
+
+func TestPostStatusError(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write([]byte(`{"error": "Validation failed: Text character limit of 500 exceeded"}`))
+	}))
+	defer s.Close()
+
+	_, err := NewClient(s.URL, "token").PostStatus(context.Background(), Status{Text: "too long"}, "")
+	require.ErrorContains(t, err, "character limit of 500 exceeded")
+}
+
+func TestPostStatusRateLimit(t *testing.T) {
+	reset := time.Now().Add(200 * time.Millisecond)
+
+	var requests []time.Time
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, time.Now())
+
+		w.Header().Set("X-RateLimit-Limit", "300")
+		w.Header().Set("X-RateLimit-Reset", reset.UTC().Format(time.RFC3339Nano))
+
+		// the first request is rate limited, the second one uses the last request left.
+		if len(requests) == 1 {
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"error": "Too many requests"}`))
+			return
+		}
+
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		reset = time.Now().Add(200 * time.Millisecond)
+		w.Header().Set("X-RateLimit-Reset", reset.UTC().Format(time.RFC3339Nano))
+		w.Write([]byte(`{"id": "110"}`))
+	}))
+	defer s.Close()
+
+	c := NewClient(s.URL, "token")
+
+	// the status is posted again once the rate limit resets.
+	posted, err := c.PostStatus(context.Background(), Status{Text: "foo/bar"}, "")
+	require.NoError(t, err)
+	require.Equal(t, "110", posted.ID)
+	require.Len(t, requests, 2)
+	require.Greater(t, requests[1].Sub(requests[0]), 100*time.Millisecond)
+
+	// the next status waits for the reset, no request is left.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err = c.PostStatus(ctx, Status{Text: "foo/baz"}, "")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Len(t, requests, 2)
+}
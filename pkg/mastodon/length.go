@@ -0,0 +1,23 @@
+package mastodon
+
+import (
+	"regexp"
+	"unicode/utf8"
+)
+
+const (
+	// DefaultMaxChars is the length limit of the statuses of most instances.
+	DefaultMaxChars = 500
+	// urlLength is the length Mastodon counts for every link, whatever its actual length.
+	urlLength = 23
+)
+
+var urlPattern = regexp.MustCompile(`https?://\S+`)
+
+// Length returns the length of the text as Mastodon counts it,
+// every link counts as 23 characters.
+func Length(text string) int {
+	links := urlPattern.FindAllStringIndex(text, -1)
+
+	return utf8.RuneCountInString(urlPattern.ReplaceAllString(text, "")) + len(links)*urlLength
+}
@@ -0,0 +1,13 @@
+package mastodon
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLength(t *testing.T) {
+	require.Equal(t, 5, Length("héllo"))
+	require.Equal(t, 4+urlLength, Length("see https://github.com/a-very-long-owner/a-very-long-repository-name"))
+	require.Equal(t, 2*urlLength+1, Length("http://a.b\nhttps://c.d"))
+}
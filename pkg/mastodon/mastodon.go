@@ -0,0 +1,140 @@
+package mastodon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/Arturomtz8/github-inspector/pkg/pipeline"
+	"github.com/Arturomtz8/github-inspector/pkg/seen"
+	"github.com/Arturomtz8/github-inspector/pkg/templates"
+)
+
+// sinkName names the statuses in the seen store and the template directory.
+const sinkName = "mastodon"
+
+// Options changes the way PublishRepos publishes the repos.
+type Options struct {
+	pipeline.TrendingOptions
+	// Visibility of the statuses. Defaults to Public.
+	Visibility Visibility
+	// StatusLanguage is the ISO 639 code of the language of the statuses, e.g. "en".
+	// Empty lets the instance guess it.
+	StatusLanguage string
+	// ContentWarning is shown instead of the statuses until they're expanded. Empty shows the statuses.
+	ContentWarning string
+	// MaxChars is the length limit of the statuses on the instance. Defaults to DefaultMaxChars.
+	MaxChars int
+}
+
+func (o Options) maxChars() int {
+	if o.MaxChars <= 0 {
+		return DefaultMaxChars
+	}
+
+	return o.MaxChars
+}
+
+// newStatusLimiter returns the limiter of the statuses,
+// well within the 300 statuses every 3 hours Mastodon allows an account.
+func newStatusLimiter() *rate.Limiter {
+	return rate.NewLimiter(rate.Every(time.Minute), 5)
+}
+
+// PublishRepos gets the trending repos and posts the ones not published yet
+// to the account of the client, as statuses.
+// redisURI is the seen store used when opts.SeenStore is empty.
+func PublishRepos(ctx context.Context, client *Client, redisURI string, opts Options) error {
+	if opts.SeenStore == "" {
+		opts.SeenStore = redisURI
+	}
+
+	if !opts.DryRun && (client == nil || client.Instance == "" || client.Token == "") {
+		return errors.New("the instance URL and the access token are required")
+	}
+
+	sink := &pipeline.Sink{Name: sinkName, Limiter: newStatusLimiter()}
+
+	run, err := pipeline.NewTrending(opts.TrendingOptions, sink)
+	if err != nil {
+		return err
+	}
+	defer run.Close()
+
+	visibility := opts.Visibility
+	if visibility == "" {
+		visibility = Public
+	}
+
+	sink.Publisher = &publisher{
+		client: client,
+		seen:   sink.Seen,
+		status: Status{
+			Visibility:  visibility,
+			Language:    opts.StatusLanguage,
+			SpoilerText: opts.ContentWarning,
+		},
+		maxChars: opts.maxChars(),
+		dry:      opts.DryRunOutput(),
+	}
+
+	return run.Run(ctx)
+}
+
+// publisher is the Mastodon sink of the pipeline, it posts the posts as statuses.
+type publisher struct {
+	client *Client
+	seen   *pipeline.Seen
+	// status holds the settings of every status.
+	status   Status
+	maxChars int
+	// dry prints the statuses instead of posting them, if set.
+	dry     io.Writer
+	printed int
+}
+
+// Publish posts the status of the post and commits its repo once the instance accepted it.
+func (p *publisher) Publish(ctx context.Context, post *pipeline.Post) error {
+	status := p.status
+	status.Text = templates.Fit(post.Content, p.maxChars, Length)
+
+	if p.dry != nil {
+		return p.print(status)
+	}
+
+	// The idempotency key is the one of the repo in the run,
+	// a status retried after the rate limit is posted once.
+	posted, err := p.client.PostStatus(ctx, status, post.Key+":"+p.seen.Token())
+	if err != nil {
+		p.seen.Unreserve(ctx, post.Key)
+		return err
+	}
+
+	log.Printf("published to %s", posted.URL)
+
+	return p.seen.Commit(ctx, post.Key, p.seen.Token(), seen.Record{
+		URL:         post.Repo.HtmlURL,
+		Stars:       post.Repo.StargazersCount,
+		PublishedAt: time.Now(),
+		EventID:     posted.ID,
+	})
+}
+
+// print writes the status to the output of the dry run, as it would appear.
+func (p *publisher) print(s Status) error {
+	p.printed++
+
+	header := fmt.Sprintf("%d. status, %s, %d characters", p.printed, s.Visibility, Length(s.Text))
+	if s.SpoilerText != "" {
+		header += fmt.Sprintf(", CW %q", s.SpoilerText)
+	}
+
+	_, err := fmt.Fprintf(p.dry, "── %s ──\n%s\n\n", header, s.Text)
+
+	return err
+}
@@ -0,0 +1,94 @@
+package mastodon
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/Arturomtz8/github-inspector/pkg/github"
+	"github.com/Arturomtz8/github-inspector/pkg/pipeline"
+	"github.com/Arturomtz8/github-inspector/pkg/seen"
+)
+
+func TestPublish(t *testing.T) {
+	ctx := context.Background()
+
+	var texts []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		texts = append(texts, r.PostForm.Get("status"))
+
+		if strings.HasPrefix(r.PostForm.Get("status"), "foo/baz") {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Write([]byte(`{"id": "110", "url": "https://example.social/@bot/110"}`))
+	}))
+	defer s.Close()
+
+	store := seen.NewMemoryStore()
+	seenRepos := pipeline.NewSeen(store, seen.Namespace{Sink: sinkName, Channel: "test", Language: "Go"})
+
+	pub := &publisher{
+		client:   NewClient(s.URL, "token"),
+		seen:     seenRepos,
+		status:   Status{Visibility: Unlisted},
+		maxChars: 60,
+	}
+
+	repos := []*github.RepoTrending{
+		{FullName: "foo/bar", HtmlURL: "https://github.com/foo/bar", StargazersCount: 100},
+		{FullName: "foo/baz", HtmlURL: "https://github.com/foo/baz", StargazersCount: 200},
+	}
+
+	candidates, err := pipeline.Dedupe(ctx, seenRepos, pipeline.DefaultRepublishPolicy(), repos)
+	require.NoError(t, err)
+	require.Len(t, candidates, 2)
+
+	long := "foo/bar: " + strings.Repeat("a good project ", 10) + "\nhttps://github.com/foo/bar"
+	require.NoError(t, pub.Publish(ctx, &pipeline.Post{Candidate: candidates[0], Content: long}))
+	require.Error(t, pub.Publish(ctx, &pipeline.Post{Candidate: candidates[1], Content: "foo/baz"}))
+
+	// the statuses fit the limit of the instance.
+	require.LessOrEqual(t, Length(texts[0]), 60)
+
+	// the published repo is committed with the ID of its status.
+	value, ok, err := store.Get(ctx, candidates[0].Key)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	record, ok := seen.ParseRecord(value)
+	require.True(t, ok)
+	require.Equal(t, "110", record.EventID)
+	require.Equal(t, 100, record.Stars)
+
+	// the repo that failed is released, a later run can publish it.
+	_, ok, err = store.Get(ctx, candidates[1].Key)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	candidates, err = pipeline.Dedupe(ctx, pipeline.NewSeen(store, seen.Namespace{Sink: sinkName, Channel: "test", Language: "Go"}), pipeline.DefaultRepublishPolicy(), repos)
+	require.NoError(t, err)
+	require.Len(t, candidates, 1)
+	require.Equal(t, "foo/baz", candidates[0].Repo.FullName)
+}
+
+func TestPublishDryRun(t *testing.T) {
+	var buf bytes.Buffer
+	pub := &publisher{
+		seen:     pipeline.NewSeen(seen.NewMemoryStore(), seen.Namespace{}),
+		status:   Status{Visibility: Public, SpoilerText: "trending repos"},
+		maxChars: DefaultMaxChars,
+		dry:      &buf,
+	}
+
+	c := &pipeline.Candidate{Repo: &github.RepoTrending{FullName: "foo/bar"}}
+	require.NoError(t, pub.Publish(context.Background(), &pipeline.Post{Candidate: c, Content: "foo/bar: a good project"}))
+	require.Equal(t, "── 1. status, public, 23 characters, CW \"trending repos\" ──\nfoo/bar: a good project\n\n", buf.String())
+}
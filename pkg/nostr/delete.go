@@ -0,0 +1,246 @@
+package nostr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/Arturomtz8/github-inspector/pkg/pipeline"
+)
+
+const (
+	// eventsKeyPrefix prefixes the Redis set holding the IDs of the events published for a repo,
+	// followed by the seen key of the repo so every namespace has its own events.
+	eventsKeyPrefix = "events:"
+	// relaysKeyPrefix prefixes the Redis set holding the relays an event still has to be deleted from,
+	// the relays that accepted it until its deletion is sent.
+	relaysKeyPrefix = "relays:"
+	// eventRepoKeyPrefix prefixes the Redis key holding the seen key of the repo an event was published for.
+	eventRepoKeyPrefix = "event-repo:"
+	// publishedEventsTTL is how long the published events are kept to be retracted.
+	publishedEventsTTL = 90 * 24 * time.Hour
+)
+
+// eventStore keeps the events published for every repo
+// and the relays that accepted them, so they can be deleted later on.
+type eventStore struct {
+	rdb *redis.Client
+}
+
+// eventsKey returns the key of the events of the repo, given by its seen key.
+func eventsKey(key string) string {
+	return eventsKeyPrefix + key
+}
+
+func relaysKey(id string) string {
+	return relaysKeyPrefix + id
+}
+
+func eventRepoKey(id string) string {
+	return eventRepoKeyPrefix + id
+}
+
+// Record stores the event published for the repo of the seen key and the relays that accepted it.
+func (s *eventStore) Record(ctx context.Context, key, id string, relays []string) error {
+	_, err := s.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.SAdd(ctx, eventsKey(key), id)
+		pipe.Expire(ctx, eventsKey(key), publishedEventsTTL)
+		pipe.Set(ctx, eventRepoKey(id), key, publishedEventsTTL)
+
+		if len(relays) != 0 {
+			pipe.SAdd(ctx, relaysKey(id), members(relays)...)
+			pipe.Expire(ctx, relaysKey(id), publishedEventsTTL)
+		}
+
+		return nil
+	})
+
+	return err
+}
+
+// Events returns the IDs of the events published for the repo of the seen key.
+func (s *eventStore) Events(ctx context.Context, key string) ([]string, error) {
+	return s.rdb.SMembers(ctx, eventsKey(key)).Result()
+}
+
+// Relays returns the relays the event still has to be deleted from.
+func (s *eventStore) Relays(ctx context.Context, id string) ([]string, error) {
+	return s.rdb.SMembers(ctx, relaysKey(id)).Result()
+}
+
+// Deleted records that the deletion of the event was accepted by the acked relays
+// and not by the failed ones, which are kept to be retried.
+// The event is forgotten once no relay is left.
+func (s *eventStore) Deleted(ctx context.Context, id string, acked, failed []string) error {
+	if len(failed) == 0 {
+		return s.Forget(ctx, id)
+	}
+
+	_, err := s.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if len(acked) != 0 {
+			pipe.SRem(ctx, relaysKey(id), members(acked)...)
+		}
+
+		pipe.SAdd(ctx, relaysKey(id), members(failed)...)
+		pipe.Expire(ctx, relaysKey(id), publishedEventsTTL)
+		return nil
+	})
+
+	return err
+}
+
+// Forget removes the event from the events of its repo.
+func (s *eventStore) Forget(ctx context.Context, id string) error {
+	key, err := s.rdb.Get(ctx, eventRepoKey(id)).Result()
+	if err != nil && err != redis.Nil {
+		return err
+	}
+
+	_, err = s.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if key != "" {
+			pipe.SRem(ctx, eventsKey(key), id)
+		}
+		pipe.Del(ctx, relaysKey(id), eventRepoKey(id))
+		return nil
+	})
+
+	return err
+}
+
+// members returns the relays as the members of a Redis set.
+func members(relays []string) []interface{} {
+	m := make([]interface{}, len(relays))
+	for i, relay := range relays {
+		m[i] = relay
+	}
+
+	return m
+}
+
+// DeleteRepo retracts every note previously published for the repo on the channel,
+// for the trending repos of the language, sending a NIP-09 deletion event
+// to the relays that accepted them. The repo stays as seen, so it's not published again.
+func DeleteRepo(ctx context.Context, sk, redisURI, channel, language, fullName, reason string) error {
+	rdb, err := newRedisClient(ctx, redisURI)
+	if err != nil {
+		return err
+	}
+	defer rdb.Close()
+
+	store := &eventStore{rdb: rdb}
+
+	key := pipeline.TrendingOptions{Channel: channel, Language: language}.Namespace(sinkName).Key(fullName)
+
+	ids, err := store.Events(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	if len(ids) == 0 {
+		return errors.New("no events found for " + fullName)
+	}
+
+	var errs []error
+	for _, id := range ids {
+		if err := deleteEvent(ctx, sk, store, id, reason); err != nil {
+			// No need to break loop, just continue to the next one.
+			log.Printf("error occurred deleting event %s: %v", id, err)
+			errs = append(errs, err)
+			continue
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// DeleteEvent retracts a single event sending a NIP-09 deletion event
+// to the relays that accepted it. If those relays are unknown,
+// the deletion is sent to every relay.
+func DeleteEvent(ctx context.Context, sk, redisURI, id, reason string) error {
+	rdb, err := newRedisClient(ctx, redisURI)
+	if err != nil {
+		return err
+	}
+	defer rdb.Close()
+
+	return deleteEvent(ctx, sk, &eventStore{rdb: rdb}, id, reason)
+}
+
+// deleteEvent sends the deletion of the event to the relays that accepted it.
+// The event is forgotten once every relay accepted the deletion,
+// the relays that didn't are kept so the deletion can be sent again.
+func deleteEvent(ctx context.Context, sk string, store *eventStore, id, reason string) error {
+	relays, err := store.Relays(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if len(relays) == 0 {
+		relays = relayURLs
+	}
+
+	ev, err := buildDeletion(sk, id, reason)
+	if err != nil {
+		return err
+	}
+
+	// The relays that failed are logged, they're told apart from the ones that accepted it below.
+	acked, _ := publishEventTo(ctx, ev, relays)
+
+	failed := make([]string, 0, len(relays))
+	for _, relay := range relays {
+		if !contains(acked, relay) {
+			failed = append(failed, relay)
+		}
+	}
+
+	if err := store.Deleted(ctx, id, acked, failed); err != nil {
+		return err
+	}
+
+	if len(failed) != 0 {
+		return fmt.Errorf("deletion of %s not accepted by %v, delete it again to retry", id, failed)
+	}
+
+	log.Printf("deletion of %s sent to %v", id, relays)
+
+	return nil
+}
+
+// contains reports whether the relay is one of the relays.
+func contains(relays []string, relay string) bool {
+	for _, r := range relays {
+		if r == relay {
+			return true
+		}
+	}
+
+	return false
+}
+
+// buildDeletion returns a signed NIP-09 deletion event (kind 5) of the given event.
+func buildDeletion(sk, id, reason string) (nostr.Event, error) {
+	pub, err := nostr.GetPublicKey(sk)
+	if err != nil {
+		return nostr.Event{}, err
+	}
+
+	ev := nostr.Event{
+		PubKey:    pub,
+		CreatedAt: nostr.Now(),
+		Kind:      nostr.KindDeletion,
+		Tags:      nostr.Tags{{"e", id}},
+		Content:   reason,
+	}
+
+	if err := ev.Sign(sk); err != nil {
+		return nostr.Event{}, err
+	}
+
+	return ev, nil
+}
@@ -0,0 +1,80 @@
+package nostr
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Arturomtz8/github-inspector/pkg/seen"
+)
+
+func TestBuildDeletion(t *testing.T) {
+	sk := nostr.GeneratePrivateKey()
+
+	ev, err := buildDeletion(sk, "event-id", "scam repo")
+	require.NoError(t, err)
+
+	require.Equal(t, nostr.KindDeletion, ev.Kind)
+	require.Equal(t, nostr.Tags{{"e", "event-id"}}, ev.Tags)
+	require.Equal(t, "scam repo", ev.Content)
+
+	ok, err := ev.CheckSignature()
+	require.NoError(t, err)
+	require.True(t, ok)
+}
+
+func TestEventStore(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping short mode")
+	}
+
+	ctx := context.Background()
+
+	rdb, err := newRedisClient(context.Background(), os.Getenv("REDIS_URI"))
+	require.NoError(t, err)
+	defer rdb.Close()
+
+	store := &eventStore{rdb: rdb}
+
+	key := seen.Namespace{Sink: sinkName, Channel: "test", Language: "Go"}.Key("danvergara/dblab")
+	relays := []string{"wss://relay.damus.io/", "wss://relay.nostr.band"}
+	require.NoError(t, store.Record(ctx, key, "event-id", relays))
+
+	ids, err := store.Events(ctx, key)
+	require.NoError(t, err)
+	require.Equal(t, []string{"event-id"}, ids)
+
+	// the events of another namespace are kept apart.
+	ids, err = store.Events(ctx, seen.Namespace{Sink: sinkName, Channel: "test", Language: "Rust"}.Key("danvergara/dblab"))
+	require.NoError(t, err)
+	require.Empty(t, ids)
+
+	got, err := store.Relays(ctx, "event-id")
+	require.NoError(t, err)
+	require.ElementsMatch(t, relays, got)
+
+	// the relays that didn't accept the deletion are kept to be retried.
+	require.NoError(t, store.Deleted(ctx, "event-id", relays[:1], relays[1:]))
+
+	got, err = store.Relays(ctx, "event-id")
+	require.NoError(t, err)
+	require.Equal(t, relays[1:], got)
+
+	ids, err = store.Events(ctx, key)
+	require.NoError(t, err)
+	require.Equal(t, []string{"event-id"}, ids)
+
+	// the event is forgotten once every relay accepted it, along with its repo.
+	require.NoError(t, store.Deleted(ctx, "event-id", relays[1:], nil))
+
+	ids, err = store.Events(ctx, key)
+	require.NoError(t, err)
+	require.Empty(t, ids)
+
+	got, err = store.Relays(ctx, "event-id")
+	require.NoError(t, err)
+	require.Empty(t, got)
+}
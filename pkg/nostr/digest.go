@@ -0,0 +1,137 @@
+package nostr
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+
+	"github.com/Arturomtz8/github-inspector/pkg/github"
+)
+
+// DigestOptions parameterises the long-form digest of the trending repos.
+type DigestOptions struct {
+	// Language is the programming language the trending list was scraped for.
+	Language string
+	// Since is the trending period, e.g. github.TimeToday.
+	Since string
+	// Date is the day the digest belongs to, it also makes up the "d" identifier.
+	Date time.Time
+	// Hashtags are added as "t" tags to the article,
+	// the ones of the language in the templates' languages.json.
+	Hashtags []string
+}
+
+// DefaultDigestOptions returns the options used by the daily Go digest.
+func DefaultDigestOptions() DigestOptions {
+	return DigestOptions{
+		Language: "Go",
+		Since:    github.TimeToday,
+		Date:     time.Now().UTC(),
+		Hashtags: []string{"golang", "programming"},
+	}
+}
+
+// identifier returns the value of the "d" tag, one per language, period and date.
+// Publishing the digest again for the same date replaces the previous article.
+func (o DigestOptions) identifier() string {
+	return fmt.Sprintf("trending-%s-%s-%s",
+		strings.ToLower(o.Language),
+		o.Since,
+		o.Date.Format("2006-01-02"),
+	)
+}
+
+// title returns the title of the article.
+func (o DigestOptions) title() string {
+	return fmt.Sprintf("Trending %s repositories (%s) - %s",
+		o.Language,
+		o.Since,
+		o.Date.Format("January 2, 2006"),
+	)
+}
+
+// PublishDigest builds the long-form digest of the given repos
+// and publishes it to Nostr relays.
+func PublishDigest(ctx context.Context, sk string, repos *github.TrendingSearchResult, opts DigestOptions) error {
+	ev, err := buildDigest(sk, repos, opts)
+	if err != nil {
+		return err
+	}
+
+	_, err = publishEvent(ctx, ev)
+	return err
+}
+
+// buildDigest returns a signed NIP-23 long-form event (kind 30023)
+// holding a markdown digest of the trending repos.
+func buildDigest(sk string, repos *github.TrendingSearchResult, opts DigestOptions) (nostr.Event, error) {
+	pub, err := nostr.GetPublicKey(sk)
+	if err != nil {
+		return nostr.Event{}, err
+	}
+
+	tags := nostr.Tags{
+		{"d", opts.identifier()},
+		{"title", opts.title()},
+		{"summary", digestSummary(repos, opts)},
+		{"published_at", fmt.Sprintf("%d", opts.Date.Unix())},
+	}
+
+	for _, hashtag := range opts.Hashtags {
+		tags = append(tags, nostr.Tag{"t", hashtag})
+	}
+
+	ev := nostr.Event{
+		PubKey:    pub,
+		CreatedAt: nostr.Now(),
+		Kind:      nostr.KindArticle,
+		Tags:      tags,
+		Content:   digestContent(repos, opts),
+	}
+
+	if err := ev.Sign(sk); err != nil {
+		return nostr.Event{}, err
+	}
+
+	return ev, nil
+}
+
+// digestSummary returns a one line summary of the digest.
+func digestSummary(repos *github.TrendingSearchResult, opts DigestOptions) string {
+	return fmt.Sprintf("The %d trending %s repositories on GitHub (%s).",
+		len(repos.Items),
+		opts.Language,
+		opts.Since,
+	)
+}
+
+// digestContent renders the trending repos as markdown.
+func digestContent(repos *github.TrendingSearchResult, opts DigestOptions) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# %s\n\n", opts.title())
+	fmt.Fprintf(&b, "%s\n\n", digestSummary(repos, opts))
+
+	for i, repo := range repos.Items {
+		fmt.Fprintf(&b, "## %d. [%s](%s)\n\n", i+1, repo.FullName, repo.HtmlURL)
+
+		if repo.Description != "" {
+			fmt.Fprintf(&b, "%s\n\n", repo.Description)
+		}
+
+		if repo.Comment != "" {
+			fmt.Fprintf(&b, "> %s\n\n", repo.Comment)
+		}
+
+		fmt.Fprintf(&b, "Author: %s | ⭐: %d\n\n", repo.Owner.Login, repo.StargazersCount)
+	}
+
+	for _, hashtag := range opts.Hashtags {
+		fmt.Fprintf(&b, "#%s ", hashtag)
+	}
+
+	return strings.TrimSpace(b.String())
+}
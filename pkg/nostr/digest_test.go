@@ -0,0 +1,70 @@
+package nostr
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Arturomtz8/github-inspector/pkg/github"
+)
+
+func TestBuildDigest(t *testing.T) {
+	sk := nostr.GeneratePrivateKey()
+
+	repos := &github.TrendingSearchResult{
+		Items: []*github.RepoTrending{
+			{
+				FullName:        "danvergara/dblab",
+				HtmlURL:         "https://github.com/danvergara/dblab",
+				Description:     "The database client every command line junkie deserves.",
+				StargazersCount: 702,
+				Owner: github.Owner{
+					Login: "danvergara",
+				},
+			},
+			{
+				FullName: "go-resty/resty",
+				HtmlURL:  "https://github.com/go-resty/resty",
+				Comment:  "Our favourite HTTP client.",
+				Owner: github.Owner{
+					Login: "go-resty",
+				},
+			},
+		},
+	}
+
+	opts := DefaultDigestOptions()
+	opts.Date = time.Date(2023, time.October, 6, 16, 0, 0, 0, time.UTC)
+
+	ev, err := buildDigest(sk, repos, opts)
+	require.NoError(t, err)
+
+	require.Equal(t, nostr.KindArticle, ev.Kind)
+	require.Equal(t, "trending-go-daily-2023-10-06", ev.Tags.GetFirst([]string{"d"}).Value())
+	require.NotEmpty(t, ev.Tags.GetFirst([]string{"title"}).Value())
+	require.NotEmpty(t, ev.Tags.GetFirst([]string{"summary"}).Value())
+
+	var hashtags []string
+	for _, tag := range ev.Tags {
+		if tag.Key() == "t" {
+			hashtags = append(hashtags, tag.Value())
+		}
+	}
+	require.Equal(t, []string{"golang", "programming"}, hashtags)
+
+	require.Contains(t, ev.Content, "[danvergara/dblab](https://github.com/danvergara/dblab)")
+	require.Contains(t, ev.Content, "[go-resty/resty](https://github.com/go-resty/resty)")
+	require.Contains(t, ev.Content, "> Our favourite HTTP client.")
+
+	ok, err := ev.CheckSignature()
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// Building the digest again for the same date keeps the identifier,
+	// so the new article replaces the old one.
+	again, err := buildDigest(sk, repos, opts)
+	require.NoError(t, err)
+	require.Equal(t, ev.Tags.GetFirst([]string{"d"}).Value(), again.Tags.GetFirst([]string{"d"}).Value())
+}
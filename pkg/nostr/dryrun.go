@@ -0,0 +1,64 @@
+package nostr
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// dryRun prints the events of a run instead of publishing them.
+type dryRun struct {
+	w io.Writer
+	// preview prints the notes as they would appear in a client,
+	// instead of the signed events as JSON.
+	preview bool
+	// printed is the number of events printed so far.
+	printed int
+}
+
+// print writes the event to the output of the dry run.
+func (d *dryRun) print(ev nostr.Event) error {
+	d.printed++
+
+	if !d.preview {
+		enc := json.NewEncoder(d.w)
+		enc.SetIndent("", "  ")
+
+		return enc.Encode(ev)
+	}
+
+	var header []string
+	switch ev.Kind {
+	case nostr.KindArticle:
+		header = append(header, "article")
+		if title := ev.Tags.GetFirst([]string{"title"}); title != nil {
+			header = append(header, fmt.Sprintf("%q", title.Value()))
+		}
+	default:
+		header = append(header, "note")
+	}
+
+	for _, tag := range ev.Tags {
+		if tag.Key() == "e" && len(tag) > 3 && tag[3] == "reply" {
+			header = append(header, "in reply to "+shortID(tag.Value()))
+		} else if tag.Key() == "e" && len(tag) > 3 && tag[3] == "root" {
+			header = append(header, "in thread "+shortID(tag.Value()))
+		}
+	}
+
+	_, err := fmt.Fprintf(d.w, "── %d. %s ──\n%s\n\n", d.printed, strings.Join(header, ", "), ev.Content)
+
+	return err
+}
+
+// shortID returns the first characters of an event ID, enough to tell events apart.
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+
+	return id
+}
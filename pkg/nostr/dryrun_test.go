@@ -0,0 +1,42 @@
+package nostr
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDryRunPrint(t *testing.T) {
+	sk := nostr.GeneratePrivateKey()
+
+	root, err := buildNote(sk, "Today's trending Go repos 🧵", nil)
+	require.NoError(t, err)
+
+	th := &thread{pub: root.PubKey, root: root.ID}
+	note, err := buildNote(sk, "foo/bar: a good project", th.replyTags())
+	require.NoError(t, err)
+
+	// the signed events are printed as JSON.
+	var buf bytes.Buffer
+	d := &dryRun{w: &buf}
+	require.NoError(t, d.print(note))
+
+	var printed nostr.Event
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &printed))
+	require.Equal(t, note.ID, printed.ID)
+
+	ok, err := printed.CheckSignature()
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// the preview shows the notes as they would appear.
+	buf.Reset()
+	d = &dryRun{w: &buf, preview: true}
+	require.NoError(t, d.print(root))
+	require.NoError(t, d.print(note))
+	require.Equal(t, "── 1. note ──\nToday's trending Go repos 🧵\n\n"+
+		"── 2. note, in thread "+root.ID[:8]+" ──\nfoo/bar: a good project\n\n", buf.String())
+}
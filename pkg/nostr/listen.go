@@ -0,0 +1,261 @@
+package nostr
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip10"
+	"golang.org/x/time/rate"
+
+	"github.com/Arturomtz8/github-inspector/pkg/github"
+)
+
+const (
+	// maxReplyRepos is the number of repos included in a reply.
+	maxReplyRepos = 5
+	// requesterInterval is the minimum time between two answered requests
+	// coming from the same pubkey.
+	requesterInterval = time.Minute
+	// maxRequesters is the number of pubkeys the limiter of the requests keeps track of at once.
+	maxRequesters = 10000
+)
+
+// trendingRequest is a request parsed from a note that mentions the bot.
+type trendingRequest struct {
+	Language string
+	Since    string
+}
+
+// Listen subscribes to the notes that mention the bot
+// and answers requests like "trending rust weekly"
+// with a reply holding the trending repos of that language.
+// It runs until the context is canceled.
+func Listen(ctx context.Context, sk string) error {
+	pub, err := nostr.GetPublicKey(sk)
+	if err != nil {
+		return err
+	}
+
+	since := nostr.Now()
+	filters := nostr.Filters{{
+		Kinds: []int{nostr.KindTextNote},
+		Tags:  nostr.TagMap{"p": []string{pub}},
+		Since: &since,
+	}}
+
+	limiter := newRequesterLimiter(requesterInterval)
+
+	pool := nostr.NewSimplePool(ctx)
+	for ev := range pool.SubMany(ctx, relayURLs, filters) {
+		// Never answer the bot's own notes.
+		if ev.PubKey == pub {
+			continue
+		}
+
+		req, ok := parseTrendingRequest(ev.Content)
+		if !ok {
+			continue
+		}
+
+		if !limiter.Allow(ev.PubKey) {
+			log.Printf("%s is being rate limited, skipping event %s", ev.PubKey, ev.ID)
+			continue
+		}
+
+		if err := answerMention(ctx, sk, ev, req); err != nil {
+			// No need to stop listening, just continue to the next one.
+			log.Printf("error occurred answering event %s: %v", ev.ID, err)
+			continue
+		}
+	}
+
+	return ctx.Err()
+}
+
+// answerMention gets the trending repos asked for and replies to the mention.
+func answerMention(ctx context.Context, sk string, mention *nostr.Event, req trendingRequest) error {
+	repos, err := github.GetTrendingRepos(req.Since, req.Language)
+	if err != nil {
+		return err
+	}
+
+	pub, _ := nostr.GetPublicKey(sk)
+
+	ev := nostr.Event{
+		PubKey:    pub,
+		CreatedAt: nostr.Now(),
+		Kind:      nostr.KindTextNote,
+		Tags:      mentionReplyTags(mention),
+		Content:   replyContent(req, repos),
+	}
+
+	if err := ev.Sign(sk); err != nil {
+		return err
+	}
+
+	_, err = publishEvent(ctx, ev)
+	return err
+}
+
+// mentionReplyTags returns the NIP-10 tags of a reply to the given mention.
+// If the mention is already part of a thread, the reply stays in that thread.
+func mentionReplyTags(mention *nostr.Event) nostr.Tags {
+	var tags nostr.Tags
+
+	if root := nip10.GetThreadRoot(mention.Tags); root != nil {
+		tags = append(tags,
+			nostr.Tag{"e", root.Value(), "", "root"},
+			nostr.Tag{"e", mention.ID, "", "reply"},
+		)
+	} else {
+		tags = append(tags, nostr.Tag{"e", mention.ID, "", "root"})
+	}
+
+	tags = append(tags, nostr.Tag{"p", mention.PubKey})
+
+	return tags
+}
+
+// replyContent renders the trending repos for a reply.
+func replyContent(req trendingRequest, repos *github.TrendingSearchResult) string {
+	if len(repos.Items) == 0 {
+		return fmt.Sprintf("No trending %s repos found (%s).", req.Language, req.Since)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Trending %s repos (%s):\n", req.Language, req.Since)
+
+	for i, repo := range repos.Items {
+		if i == maxReplyRepos {
+			break
+		}
+
+		fmt.Fprintf(&b, "\n%d. %s ⭐: %d\n%s\n", i+1, repo.FullName, repo.StargazersCount, repo.HtmlURL)
+	}
+
+	return b.String()
+}
+
+// parseTrendingRequest looks for requests like "trending rust weekly"
+// in the content of a note. The language follows the "trending" keyword,
+// the period is optional and defaults to daily.
+func parseTrendingRequest(content string) (trendingRequest, bool) {
+	words := strings.Fields(strings.ToLower(content))
+
+	for i, word := range words {
+		if word != "trending" {
+			continue
+		}
+
+		args := words[i+1:]
+		if len(args) == 0 {
+			return trendingRequest{}, false
+		}
+
+		language := strings.Trim(args[0], ".,!?")
+		if language == "" {
+			return trendingRequest{}, false
+		}
+
+		req := trendingRequest{
+			Language: language,
+			Since:    github.TimeToday,
+		}
+
+		for _, arg := range args[1:] {
+			if since, ok := parseSince(arg); ok {
+				req.Since = since
+				break
+			}
+		}
+
+		return req, true
+	}
+
+	return trendingRequest{}, false
+}
+
+// parseSince maps the words people use for a period to the values GitHub understands.
+func parseSince(word string) (string, bool) {
+	switch strings.Trim(word, ".,!?") {
+	case "daily", "day", "today":
+		return github.TimeToday, true
+	case "weekly", "week":
+		return github.TimeWeek, true
+	case "monthly", "month":
+		return github.TimeMonth, true
+	}
+
+	return "", false
+}
+
+// requesterLimiter rate limits the requests of every pubkey on its own.
+// The pubkeys idle for longer than the interval are forgotten,
+// and at most maxRequesters are tracked at once.
+type requesterLimiter struct {
+	mu         sync.Mutex
+	every      time.Duration
+	requesters map[string]*requester
+	swept      time.Time
+}
+
+// requester is the limit of a pubkey and the time of its last request.
+type requester struct {
+	limiter *rate.Limiter
+	last    time.Time
+}
+
+func newRequesterLimiter(every time.Duration) *requesterLimiter {
+	return &requesterLimiter{
+		every:      every,
+		requesters: make(map[string]*requester),
+	}
+}
+
+// Allow reports whether the requester can be answered right now.
+func (l *requesterLimiter) Allow(pubkey string) bool {
+	return l.allowAt(pubkey, time.Now())
+}
+
+// allowAt reports whether the requester can be answered at the given time.
+// A pubkey showing up while maxRequesters are tracked is not answered,
+// so a flood of new pubkeys can't grow the limiter without bound.
+func (l *requesterLimiter) allowAt(pubkey string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.swept) >= l.every {
+		l.sweep(now)
+	}
+
+	r, ok := l.requesters[pubkey]
+	if !ok {
+		if len(l.requesters) >= maxRequesters {
+			return false
+		}
+
+		r = &requester{limiter: rate.NewLimiter(rate.Every(l.every), 1)}
+		l.requesters[pubkey] = r
+	}
+
+	r.last = now
+
+	return r.limiter.AllowN(now, 1)
+}
+
+// sweep forgets the pubkeys without requests for the whole interval,
+// their limit is full again and a new one would be the same.
+func (l *requesterLimiter) sweep(now time.Time) {
+	for pubkey, r := range l.requesters {
+		if now.Sub(r.last) >= l.every {
+			delete(l.requesters, pubkey)
+		}
+	}
+
+	l.swept = now
+}
@@ -0,0 +1,114 @@
+package nostr
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+
+	"github.com/Arturomtz8/github-inspector/pkg/github"
+)
+
+func TestParseTrendingRequest(t *testing.T) {
+	type expected struct {
+		output trendingRequest
+		ok     bool
+	}
+	var tests = []struct {
+		name     string
+		input    string
+		expected expected
+	}{
+		{
+			name:  "language and period",
+			input: "nostr:npub1f2smj66w3gu2apw3sgapxjwfxgeknp6ae9g8spy7nq6ka9wp388quctf8n trending rust weekly",
+			expected: expected{
+				output: trendingRequest{Language: "rust", Since: github.TimeWeek},
+				ok:     true,
+			},
+		},
+		{
+			name:  "default period",
+			input: "Trending Python please!",
+			expected: expected{
+				output: trendingRequest{Language: "python", Since: github.TimeToday},
+				ok:     true,
+			},
+		},
+		{
+			name:  "punctuation",
+			input: "what's trending zig this month?",
+			expected: expected{
+				output: trendingRequest{Language: "zig", Since: github.TimeMonth},
+				ok:     true,
+			},
+		},
+		{
+			name:  "missing language",
+			input: "show me what's trending",
+		},
+		{
+			name:  "no request",
+			input: "gm",
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := parseTrendingRequest(tc.input)
+			require.Equal(t, tc.expected.ok, ok)
+			require.Equal(t, tc.expected.output, got)
+		})
+	}
+}
+
+func TestMentionReplyTags(t *testing.T) {
+	mention := &nostr.Event{ID: "mention-id", PubKey: "requester"}
+	require.Equal(t, nostr.Tags{
+		{"e", "mention-id", "", "root"},
+		{"p", "requester"},
+	}, mentionReplyTags(mention))
+
+	mention.Tags = nostr.Tags{{"e", "root-id", "", "root"}}
+	require.Equal(t, nostr.Tags{
+		{"e", "root-id", "", "root"},
+		{"e", "mention-id", "", "reply"},
+		{"p", "requester"},
+	}, mentionReplyTags(mention))
+}
+
+func TestRequesterLimiter(t *testing.T) {
+	limiter := newRequesterLimiter(time.Hour)
+
+	require.True(t, limiter.Allow("alice"))
+	require.False(t, limiter.Allow("alice"))
+	// every requester has its own limit.
+	require.True(t, limiter.Allow("bob"))
+}
+
+func TestRequesterLimiterSweep(t *testing.T) {
+	limiter := newRequesterLimiter(time.Minute)
+	now := time.Now()
+
+	require.True(t, limiter.allowAt("alice", now))
+	require.True(t, limiter.allowAt("bob", now.Add(30*time.Second)))
+
+	// alice is idle for the whole interval and forgotten, bob is still limited.
+	require.False(t, limiter.allowAt("bob", now.Add(70*time.Second)))
+	require.Len(t, limiter.requesters, 1)
+	require.Contains(t, limiter.requesters, "bob")
+
+	// new pubkeys aren't answered once the limiter is full.
+	for i := len(limiter.requesters); i < maxRequesters; i++ {
+		limiter.requesters[fmt.Sprint(i)] = &requester{limiter: rate.NewLimiter(rate.Every(time.Minute), 1), last: now.Add(70 * time.Second)}
+	}
+	require.False(t, limiter.allowAt("carol", now.Add(80*time.Second)))
+
+	// they are once the idle ones are forgotten.
+	require.True(t, limiter.allowAt("carol", now.Add(3*time.Minute)))
+	require.Len(t, limiter.requesters, 1)
+}
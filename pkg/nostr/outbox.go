@@ -0,0 +1,381 @@
+package nostr
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/Arturomtz8/github-inspector/pkg/lock"
+	"github.com/Arturomtz8/github-inspector/pkg/pipeline"
+	"github.com/Arturomtz8/github-inspector/pkg/seen"
+)
+
+const (
+	// outboxKeyPrefix prefixes the Redis key holding an outbox entry.
+	outboxKeyPrefix = "outbox:"
+	// outboxPendingKey is the Redis sorted set of the pending entries,
+	// scored by the time of their next delivery attempt.
+	outboxPendingKey = "outbox-pending"
+	// outboxMaxAttempts is the number of attempts before an entry is dropped.
+	outboxMaxAttempts = 8
+	// outboxBaseBackoff is the wait after the first failed attempt,
+	// it doubles on every attempt.
+	outboxBaseBackoff = time.Minute
+	// outboxMaxBackoff caps the wait between two attempts.
+	outboxMaxBackoff = 6 * time.Hour
+	// defaultQuorum is the number of relays that have to acknowledge a note
+	// for its repo to count as published.
+	defaultQuorum = 2
+)
+
+// outboxEntry is a signed event and its delivery state on every relay.
+type outboxEntry struct {
+	Event nostr.Event `json:"event"`
+	// Repo is the full name of the repo the event was published for, if any.
+	Repo      string `json:"repo,omitempty"`
+	SeenKey   string `json:"seen_key,omitempty"`
+	RepoURL   string `json:"repo_url,omitempty"`
+	RepoStars int    `json:"repo_stars,omitempty"`
+	// Token identifies the run that reserved the repo.
+	Token string `json:"token,omitempty"`
+	// Relays tells whether every relay acknowledged the event.
+	Relays    map[string]bool `json:"relays"`
+	Quorum    int             `json:"quorum"`
+	Attempts  int             `json:"attempts"`
+	Published bool            `json:"published"`
+}
+
+func newOutboxEntry(c *pipeline.Candidate, token string, ev nostr.Event, relays []string, quorum int) *outboxEntry {
+	entry := &outboxEntry{
+		Event:  ev,
+		Relays: make(map[string]bool, len(relays)),
+		Quorum: quorum,
+	}
+
+	if c != nil {
+		repo := c.Repo
+		entry.Repo = repo.FullName
+		entry.SeenKey = c.Key
+		entry.RepoURL = repo.HtmlURL
+		entry.RepoStars = repo.StargazersCount
+		entry.Token = token
+	}
+
+	for _, relay := range relays {
+		entry.Relays[relay] = false
+	}
+
+	return entry
+}
+
+// acked returns the relays that acknowledged the event.
+func (e *outboxEntry) acked() []string {
+	var relays []string
+	for relay, ok := range e.Relays {
+		if ok {
+			relays = append(relays, relay)
+		}
+	}
+
+	return relays
+}
+
+// pending returns the relays the event still has to be delivered to.
+func (e *outboxEntry) pending() []string {
+	var relays []string
+	for relay, ok := range e.Relays {
+		if !ok {
+			relays = append(relays, relay)
+		}
+	}
+
+	return relays
+}
+
+// backoff returns the wait before the next attempt,
+// after the given number of failed attempts.
+func backoff(attempts int) time.Duration {
+	if attempts <= 0 {
+		return 0
+	}
+
+	d := outboxBaseBackoff
+	for i := 1; i < attempts; i++ {
+		d *= 2
+		if d >= outboxMaxBackoff {
+			return outboxMaxBackoff
+		}
+	}
+
+	return d
+}
+
+// addScript stores a new outbox entry and schedules its first attempt,
+// only while the run holds the lock of its channel, see lock.NewFencedScript.
+var addScript = lock.NewFencedScript(`
+redis.call("SET", KEYS[3], ARGV[3])
+redis.call("ZADD", KEYS[4], ARGV[4], ARGV[5])
+return 1
+`)
+
+// outbox is a Redis-backed queue of signed events,
+// every event is retried on the relays that didn't acknowledge it.
+type outbox struct {
+	rdb    *redis.Client
+	seen   *pipeline.Seen
+	events *eventStore
+	// lock is the lock of the run, if any: the events are only added while it's held
+	// with its fencing token, so a run that was paused past its lease doesn't publish anymore.
+	// It has to be held on the Redis server of the outbox.
+	lock *lock.Lock
+}
+
+func outboxKey(id string) string {
+	return outboxKeyPrefix + id
+}
+
+// Send stores the event in the outbox and makes the first delivery attempt.
+// The relays that fail are retried later by Flush.
+// The repo of the candidate, if any, has to be reserved by this run.
+func (o *outbox) Send(ctx context.Context, c *pipeline.Candidate, ev nostr.Event, quorum int) error {
+	entry, err := o.add(ctx, c, ev, quorum)
+	if err != nil {
+		return err
+	}
+
+	return o.deliver(ctx, entry)
+}
+
+// add stores the event in the outbox without delivering it,
+// from then on the event is retried until it reaches the quorum.
+func (o *outbox) add(ctx context.Context, c *pipeline.Candidate, ev nostr.Event, quorum int) (*outboxEntry, error) {
+	entry := newOutboxEntry(c, o.seen.Token(), ev, relayURLs, quorum)
+
+	if o.lock == nil {
+		if err := o.save(ctx, entry, time.Now()); err != nil {
+			return nil, err
+		}
+
+		return entry, nil
+	}
+
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []string{outboxKey(ev.ID), outboxPendingKey}
+	if err := o.lock.RunFenced(ctx, addScript, keys, b, time.Now().Unix(), ev.ID); err != nil {
+		return nil, fmt.Errorf("adding event %s to the outbox with fencing token %d: %w", ev.ID, o.lock.Fence(), err)
+	}
+
+	return entry, nil
+}
+
+// Flush redelivers the pending events whose next attempt is due.
+// If all is true, the backoff is ignored and every pending event is redelivered.
+func (o *outbox) Flush(ctx context.Context, all bool) error {
+	until := "+inf"
+	if !all {
+		until = strconv.FormatInt(time.Now().Unix(), 10)
+	}
+
+	ids, err := o.rdb.ZRangeByScore(ctx, outboxPendingKey, &redis.ZRangeBy{
+		Min: "-inf",
+		Max: until,
+	}).Result()
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		entry, err := o.load(ctx, id)
+		if err == redis.Nil {
+			// The entry is gone, there's nothing to deliver anymore.
+			o.rdb.ZRem(ctx, outboxPendingKey, id)
+			continue
+		} else if err != nil {
+			log.Printf("error occurred loading outbox entry %s: %v", id, err)
+			continue
+		}
+
+		if err := o.deliver(ctx, entry); err != nil {
+			// No need to break loop, just continue to the next one.
+			log.Printf("error occurred delivering event %s: %v", id, err)
+			continue
+		}
+	}
+
+	return nil
+}
+
+// deliver sends the event to the relays that didn't acknowledge it yet
+// and updates its delivery state.
+func (o *outbox) deliver(ctx context.Context, entry *outboxEntry) error {
+	for _, url := range entry.pending() {
+		if err := publishToRelay(ctx, entry.Event, url); err != nil {
+			// Moves on to the next relay.
+			log.Printf("error publishing event %s to %s: %v", entry.Event.ID, url, err)
+			continue
+		}
+
+		entry.Relays[url] = true
+	}
+
+	entry.Attempts++
+
+	if !entry.Published && len(entry.acked()) >= entry.Quorum {
+		entry.Published = true
+
+		if entry.Repo != "" {
+			record := seen.Record{
+				URL:         entry.RepoURL,
+				Stars:       entry.RepoStars,
+				PublishedAt: time.Now(),
+				EventID:     entry.Event.ID,
+			}
+
+			if err := o.seen.Commit(ctx, entry.SeenKey, entry.Token, record); err != nil {
+				log.Printf("error occurred committing %s as seen: %v", entry.Repo, err)
+			}
+		}
+	}
+
+	// The event is kept to be able to retract the repo later on.
+	if entry.Published && entry.Repo != "" {
+		if err := o.events.Record(ctx, entry.SeenKey, entry.Event.ID, entry.acked()); err != nil {
+			log.Printf("error occurred storing event of %s: %v", entry.Repo, err)
+		}
+	}
+
+	if len(entry.pending()) == 0 {
+		return o.remove(ctx, entry)
+	}
+
+	if entry.Attempts >= outboxMaxAttempts {
+		log.Printf("giving up on event %s after %d attempts", entry.Event.ID, entry.Attempts)
+
+		if !entry.Published && entry.Repo != "" {
+			// Release the repo, so a later run can publish it again.
+			if err := o.seen.Release(ctx, entry.SeenKey, entry.Token); err != nil {
+				log.Printf("error occurred releasing %s: %v", entry.Repo, err)
+			}
+		}
+
+		return o.remove(ctx, entry)
+	}
+
+	next := backoff(entry.Attempts)
+	if err := o.save(ctx, entry, time.Now().Add(next)); err != nil {
+		return err
+	}
+
+	if !entry.Published {
+		// Keep the repo reserved until the next attempt.
+		if entry.Repo != "" {
+			if err := o.seen.Extend(ctx, entry.SeenKey, entry.Token, next+pipeline.ReservationLease); err != nil {
+				log.Printf("error occurred extending reservation of %s: %v", entry.Repo, err)
+			}
+		}
+
+		return fmt.Errorf("event %s acknowledged by %d of %d relays, will be retried",
+			entry.Event.ID, len(entry.acked()), entry.Quorum)
+	}
+
+	return nil
+}
+
+// save stores the entry and schedules its next attempt.
+func (o *outbox) save(ctx context.Context, entry *outboxEntry, next time.Time) error {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	_, err = o.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, outboxKey(entry.Event.ID), b, 0)
+		pipe.ZAdd(ctx, outboxPendingKey, redis.Z{
+			Score:  float64(next.Unix()),
+			Member: entry.Event.ID,
+		})
+		return nil
+	})
+
+	return err
+}
+
+// load returns the entry of the given event.
+func (o *outbox) load(ctx context.Context, id string) (*outboxEntry, error) {
+	b, err := o.rdb.Get(ctx, outboxKey(id)).Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	var entry outboxEntry
+	if err := json.Unmarshal(b, &entry); err != nil {
+		return nil, err
+	}
+
+	return &entry, nil
+}
+
+// remove drops the entry from the outbox.
+func (o *outbox) remove(ctx context.Context, entry *outboxEntry) error {
+	_, err := o.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, outboxKey(entry.Event.ID))
+		pipe.ZRem(ctx, outboxPendingKey, entry.Event.ID)
+		return nil
+	})
+
+	return err
+}
+
+// delivered reports whether at least one relay acknowledged the event, if it's still in the outbox.
+func (o *outbox) delivered(ctx context.Context, id string) (bool, error) {
+	entry, err := o.load(ctx, id)
+	if err == redis.Nil {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return len(entry.acked()) != 0, nil
+}
+
+// drop removes the event from the outbox, it won't be delivered anymore.
+func (o *outbox) drop(ctx context.Context, id string) error {
+	return o.remove(ctx, &outboxEntry{Event: nostr.Event{ID: id}})
+}
+
+// FlushOutbox redelivers the events whose delivery failed on some relays.
+// seenURI is the store the repos were reserved in, it defaults to the Redis URI.
+// If all is true, the backoff is ignored.
+func FlushOutbox(ctx context.Context, redisURI, seenURI string, all bool) error {
+	rdb, err := newRedisClient(ctx, redisURI)
+	if err != nil {
+		return err
+	}
+	defer rdb.Close()
+
+	store, err := openSeenStore(rdb, seenURI)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	box := &outbox{rdb: rdb, seen: keyedSeen(store), events: &eventStore{rdb: rdb}}
+
+	return box.Flush(ctx, all)
+}
+
+// keyedSeen returns the seen repos of the notes left by other runs, in the outbox or a queue.
+// The notes hold the keys of their repos, so the namespace of the publishing run doesn't matter.
+func keyedSeen(store seen.Store) *pipeline.Seen {
+	return pipeline.NewSeen(store, seen.Namespace{})
+}
@@ -0,0 +1,92 @@
+package nostr
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Arturomtz8/github-inspector/pkg/github"
+	"github.com/Arturomtz8/github-inspector/pkg/pipeline"
+	"github.com/Arturomtz8/github-inspector/pkg/seen"
+)
+
+func TestBackoff(t *testing.T) {
+	require.Equal(t, time.Duration(0), backoff(0))
+	require.Equal(t, time.Minute, backoff(1))
+	require.Equal(t, 2*time.Minute, backoff(2))
+	require.Equal(t, 4*time.Minute, backoff(3))
+	require.Equal(t, outboxMaxBackoff, backoff(20))
+}
+
+func TestOutboxEntry(t *testing.T) {
+	relays := []string{"wss://a", "wss://b", "wss://c"}
+	c := &pipeline.Candidate{
+		Repo: &github.RepoTrending{FullName: "danvergara/dblab", HtmlURL: "https://github.com/danvergara/dblab"},
+		Key:  "seen:nostr:default:go:danvergara/dblab",
+	}
+	entry := newOutboxEntry(c, "token", nostr.Event{ID: "event-id"}, relays, 2)
+	require.Equal(t, "danvergara/dblab", entry.Repo)
+	require.Equal(t, "seen:nostr:default:go:danvergara/dblab", entry.SeenKey)
+	require.Equal(t, "token", entry.Token)
+
+	require.ElementsMatch(t, relays, entry.pending())
+	require.Empty(t, entry.acked())
+
+	entry.Relays["wss://b"] = true
+	require.ElementsMatch(t, []string{"wss://a", "wss://c"}, entry.pending())
+	require.Equal(t, []string{"wss://b"}, entry.acked())
+}
+
+func TestOutboxSaveLoad(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping short mode")
+	}
+
+	ctx := context.Background()
+
+	rdb, err := newRedisClient(context.Background(), os.Getenv("REDIS_URI"))
+	require.NoError(t, err)
+	defer rdb.Close()
+
+	box := &outbox{rdb: rdb, seen: pipeline.NewSeen(seen.NewMemoryStore(), seen.Namespace{}), events: &eventStore{rdb: rdb}}
+
+	ev, err := buildNote(nostr.GeneratePrivateKey(), "a good project", nil)
+	require.NoError(t, err)
+
+	c := &pipeline.Candidate{
+		Repo: &github.RepoTrending{FullName: "foo/bar", HtmlURL: "https://github.com/foo/bar"},
+		Key:  box.seen.Key("foo/bar"),
+	}
+	entry := newOutboxEntry(c, box.seen.Token(), ev, relayURLs, 2)
+	entry.Relays[relayURLs[0]] = true
+	require.NoError(t, box.save(ctx, entry, time.Now()))
+
+	got, err := box.load(ctx, ev.ID)
+	require.NoError(t, err)
+	require.Equal(t, entry.Repo, got.Repo)
+	require.Equal(t, entry.Relays, got.Relays)
+	require.Equal(t, ev.ID, got.Event.ID)
+
+	ok, err := got.Event.CheckSignature()
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	delivered, err := box.delivered(ctx, ev.ID)
+	require.NoError(t, err)
+	require.True(t, delivered, "a relay acknowledged the event")
+
+	require.NoError(t, box.drop(ctx, ev.ID))
+
+	delivered, err = box.delivered(ctx, ev.ID)
+	require.NoError(t, err)
+	require.False(t, delivered, "the event isn't in the outbox anymore")
+
+	require.NoError(t, box.save(ctx, entry, time.Now()))
+	require.NoError(t, box.remove(ctx, entry))
+	_, err = box.load(ctx, ev.ID)
+	require.Error(t, err)
+}
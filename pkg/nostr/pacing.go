@@ -0,0 +1,476 @@
+package nostr
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/time/rate"
+
+	"github.com/Arturomtz8/github-inspector/pkg/github"
+	"github.com/Arturomtz8/github-inspector/pkg/lock"
+	"github.com/Arturomtz8/github-inspector/pkg/pipeline"
+	"github.com/Arturomtz8/github-inspector/pkg/seen"
+)
+
+const (
+	// pacedKeyPrefix prefixes the Redis hash of the paced notes of a channel, by seen key.
+	pacedKeyPrefix = "paced:"
+	// pacedDueKeyPrefix prefixes the Redis sorted set of the paced notes of a channel,
+	// scored by the time they're due.
+	pacedDueKeyPrefix = "paced-due:"
+	// pacedThreadKeyPrefix prefixes the Redis key holding the ID of the last paced reply
+	// of a thread, by the ID of its root.
+	pacedThreadKeyPrefix = "paced-thread:"
+	// pacedThreadTTL is how long the last reply of a thread is kept, longer than a window.
+	pacedThreadTTL = 48 * time.Hour
+	// pacedRetryDelay is how long a note that couldn't be sent waits before it's tried again,
+	// e.g. while another run holds the reservation of its repo.
+	pacedRetryDelay = 5 * time.Minute
+)
+
+// PacingOptions spreads the notes of a run over a daily time window
+// instead of publishing them in a burst.
+type PacingOptions struct {
+	// Start and End are the times of day the window opens and closes at,
+	// as durations since midnight. A window ending before it starts ends the next day.
+	Start, End time.Duration
+	// Location is the time zone of the window. Defaults to UTC.
+	Location *time.Location
+	// Jitter posts every note at a random time of its share of the window
+	// instead of evenly spaced.
+	Jitter bool
+}
+
+// ParsePacingWindow parses a window like "16:00-22:00".
+func ParsePacingWindow(window string) (PacingOptions, error) {
+	from, to, ok := strings.Cut(window, "-")
+	if !ok {
+		return PacingOptions{}, fmt.Errorf("invalid window %q, expected e.g. 16:00-22:00", window)
+	}
+
+	start, err := parseTimeOfDay(from)
+	if err != nil {
+		return PacingOptions{}, fmt.Errorf("invalid window %q: %w", window, err)
+	}
+
+	end, err := parseTimeOfDay(to)
+	if err != nil {
+		return PacingOptions{}, fmt.Errorf("invalid window %q: %w", window, err)
+	}
+
+	if start == end {
+		return PacingOptions{}, fmt.Errorf("invalid window %q, it's empty", window)
+	}
+
+	return PacingOptions{Start: start, End: end}, nil
+}
+
+// parseTimeOfDay parses "15:04" into the duration since midnight.
+func parseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q", s)
+	}
+
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func (p PacingOptions) location() *time.Location {
+	if p.Location == nil {
+		return time.UTC
+	}
+
+	return p.Location
+}
+
+// at returns the time of day d of the day of t.
+func (p PacingOptions) at(t time.Time, d time.Duration) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), int(d/time.Hour), int(d%time.Hour/time.Minute), 0, 0, t.Location())
+}
+
+// window returns what's left of the current window at now,
+// or the next window if now is out of it.
+func (p PacingOptions) window(now time.Time) (start, end time.Time) {
+	local := now.In(p.location())
+
+	start, end = p.at(local, p.Start), p.at(local, p.End)
+	if !end.After(start) {
+		end = end.AddDate(0, 0, 1)
+
+		// The window crossing midnight that opened yesterday may still be open.
+		if yesterday := end.AddDate(0, 0, -1); now.Before(yesterday) {
+			start, end = start.AddDate(0, 0, -1), yesterday
+		}
+	}
+
+	if !now.Before(end) {
+		start, end = start.AddDate(0, 0, 1), end.AddDate(0, 0, 1)
+	}
+
+	if start.Before(now) {
+		start = now
+	}
+
+	return start, end
+}
+
+// slots returns the times n notes are due at, spread over the window.
+func (p PacingOptions) slots(now time.Time, n int) []time.Time {
+	if n == 0 {
+		return nil
+	}
+
+	start, end := p.window(now)
+	share := end.Sub(start) / time.Duration(n)
+
+	slots := make([]time.Time, n)
+	for i := range slots {
+		slots[i] = start.Add(time.Duration(i) * share)
+
+		if p.Jitter && share > 0 {
+			slots[i] = slots[i].Add(time.Duration(rand.Int63n(int64(share))))
+		}
+	}
+
+	return slots
+}
+
+// pacedNote is a rendered note waiting for its time to be published.
+type pacedNote struct {
+	Repo      string `json:"repo"`
+	SeenKey   string `json:"seen_key"`
+	RepoURL   string `json:"repo_url"`
+	RepoStars int    `json:"repo_stars"`
+	Content   string `json:"content"`
+	// Tags are the tags of the notes queued before the thread was kept along with them.
+	Tags nostr.Tags `json:"tags,omitempty"`
+	// Root and Author are the ID and author of the root of the thread the note replies to, if any.
+	// The note replies to the last note of the thread sent before it.
+	Root     string    `json:"root,omitempty"`
+	Author   string    `json:"author,omitempty"`
+	Due      time.Time `json:"due"`
+	QueuedAt time.Time `json:"queued_at"`
+}
+
+// candidate returns the candidate the note was rendered for.
+func (n pacedNote) candidate() *pipeline.Candidate {
+	return &pipeline.Candidate{
+		Repo: &github.RepoTrending{
+			FullName:        n.Repo,
+			HtmlURL:         n.RepoURL,
+			StargazersCount: n.RepoStars,
+		},
+		Key: n.SeenKey,
+	}
+}
+
+// pacedQueue keeps the paced notes of a channel in Redis,
+// so the notes left are published after a restart.
+type pacedQueue struct {
+	rdb     *redis.Client
+	channel string
+}
+
+func (q *pacedQueue) notesKey() string {
+	return pacedKeyPrefix + q.channel
+}
+
+func (q *pacedQueue) dueKey() string {
+	return pacedDueKeyPrefix + q.channel
+}
+
+// Add queues the note, unless its repo is already in the queue.
+// It reports whether the note was queued.
+func (q *pacedQueue) Add(ctx context.Context, note pacedNote) (bool, error) {
+	b, err := json.Marshal(note)
+	if err != nil {
+		return false, err
+	}
+
+	added, err := q.rdb.HSetNX(ctx, q.notesKey(), note.SeenKey, b).Result()
+	if err != nil || !added {
+		return false, err
+	}
+
+	return true, q.rdb.ZAdd(ctx, q.dueKey(), redis.Z{
+		Score:  float64(note.Due.UnixMilli()),
+		Member: note.SeenKey,
+	}).Err()
+}
+
+// Next returns the note due first, nil if the queue is empty.
+func (q *pacedQueue) Next(ctx context.Context) (*pacedNote, error) {
+	for {
+		keys, err := q.rdb.ZRange(ctx, q.dueKey(), 0, 0).Result()
+		if err != nil || len(keys) == 0 {
+			return nil, err
+		}
+
+		value, err := q.rdb.HGet(ctx, q.notesKey(), keys[0]).Result()
+		if err == redis.Nil {
+			// The note is gone, its entry in the sorted set is stale.
+			if err := q.Remove(ctx, keys[0]); err != nil {
+				return nil, err
+			}
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		var note pacedNote
+		if err := json.Unmarshal([]byte(value), &note); err != nil {
+			return nil, err
+		}
+
+		return &note, nil
+	}
+}
+
+// thread returns the thread the note replies to, with the last reply sent so far, nil if there's none.
+func (q *pacedQueue) thread(ctx context.Context, note *pacedNote) (*thread, error) {
+	if note.Root == "" {
+		return nil, nil
+	}
+
+	last, err := q.rdb.Get(ctx, pacedThreadKeyPrefix+note.Root).Result()
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	return &thread{pub: note.Author, root: note.Root, last: last}, nil
+}
+
+// replied records the ID of the last reply sent in the thread.
+func (q *pacedQueue) replied(ctx context.Context, th *thread, id string) error {
+	th.last = id
+
+	return q.rdb.Set(ctx, pacedThreadKeyPrefix+th.root, id, pacedThreadTTL).Err()
+}
+
+// Len returns the number of notes in the queue.
+func (q *pacedQueue) Len(ctx context.Context) (int64, error) {
+	return q.rdb.ZCard(ctx, q.dueKey()).Result()
+}
+
+// Postpone moves the note back in the queue, to fall due at the given time.
+func (q *pacedQueue) Postpone(ctx context.Context, note *pacedNote, due time.Time) error {
+	note.Due = due
+
+	b, err := json.Marshal(note)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, q.notesKey(), note.SeenKey, b)
+		pipe.ZAdd(ctx, q.dueKey(), redis.Z{Score: float64(due.UnixMilli()), Member: note.SeenKey})
+		return nil
+	})
+
+	return err
+}
+
+// Remove drops the note of the repo from the queue.
+func (q *pacedQueue) Remove(ctx context.Context, seenKey string) error {
+	_, err := q.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HDel(ctx, q.notesKey(), seenKey)
+		pipe.ZRem(ctx, q.dueKey(), seenKey)
+		return nil
+	})
+
+	return err
+}
+
+// drainPaced publishes the notes of the queue as they fall due, until the queue is empty.
+// A note that couldn't be handed over to the outbox is postponed by pacedRetryDelay.
+// It stops if another run takes the lock over, the notes left stay in the queue.
+func drainPaced(ctx context.Context, sk string, queue *pacedQueue, seenRepos *pipeline.Seen, box *outbox, limiter *rate.Limiter, quorum int, runLock *lock.Lock) error {
+	for {
+		note, err := queue.Next(ctx)
+		if err != nil {
+			return err
+		}
+
+		if note == nil {
+			return nil
+		}
+
+		if wait := time.Until(note.Due); wait > 0 {
+			log.Printf("%s is due at %s", note.Repo, note.Due.Format(time.RFC3339))
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(wait):
+			}
+		}
+
+		if err := limiter.Wait(ctx); err != nil {
+			return err
+		}
+
+		if err := checkRunLock(ctx, runLock); err != nil {
+			return err
+		}
+
+		done, err := sendPaced(ctx, sk, queue, seenRepos, box, quorum, note)
+		if errors.Is(err, lock.ErrLost) {
+			return err
+		} else if err != nil {
+			log.Printf("error occurred publishing %s: %v", note.Repo, err)
+		}
+
+		if !done {
+			if err := queue.Postpone(ctx, note, time.Now().Add(pacedRetryDelay)); err != nil {
+				return fmt.Errorf("postponing %s in the paced queue: %w", note.Repo, err)
+			}
+			continue
+		}
+
+		if err := queue.Remove(ctx, note.SeenKey); err != nil {
+			return fmt.Errorf("removing %s from the paced queue: %w", note.Repo, err)
+		}
+	}
+}
+
+// sendPaced sends the note that fell due, unless its repo was published since it was queued,
+// e.g. by a run that stopped before removing it from the queue.
+// A note of a thread replies to the last note sent in it.
+// It reports whether the note is done with, i.e. it's in the outbox or its repo was published.
+func sendPaced(ctx context.Context, sk string, queue *pacedQueue, seenRepos *pipeline.Seen, box *outbox, quorum int, note *pacedNote) (bool, error) {
+	published, err := publishedSince(ctx, seenRepos, note.SeenKey, note.QueuedAt)
+	if err != nil {
+		return false, err
+	}
+
+	if published {
+		log.Printf("%s was published since its note was queued, dropping the note", note.Repo)
+		return true, nil
+	}
+
+	th, err := queue.thread(ctx, note)
+	if err != nil {
+		return false, err
+	}
+
+	tags := note.Tags
+	if th != nil {
+		tags = th.replyTags()
+	}
+
+	id, err := sendQueued(ctx, sk, seenRepos, box, quorum, note.candidate(), note.Content, tags)
+	if th != nil && id != "" {
+		if err := queue.replied(ctx, th, id); err != nil {
+			log.Printf("error occurred recording the last reply of thread %s: %v", th.root, err)
+		}
+	}
+
+	return id != "", err
+}
+
+// sendQueued reserves the repo of a note that waited in a queue and hands the note over to the outbox.
+// Once in the outbox, the note is retried until it reaches the quorum.
+// It returns the ID of the note once it's in the outbox, empty if the note has to stay in its queue,
+// e.g. when another run is publishing the repo, so a later run sends it if that one fails.
+func sendQueued(ctx context.Context, sk string, seenRepos *pipeline.Seen, box *outbox, quorum int, c *pipeline.Candidate, content string, tags nostr.Tags) (string, error) {
+	reserved, err := seenRepos.ReserveMany(ctx, []string{c.Key})
+	if err != nil {
+		return "", fmt.Errorf("reserving: %w", err)
+	}
+
+	if !reserved[0] {
+		log.Printf("%s is being published by another run, its note is kept", c.Repo.FullName)
+		return "", nil
+	}
+
+	ev, err := buildNote(sk, content, tags)
+	if err != nil {
+		seenRepos.Unreserve(ctx, c.Key)
+		return "", err
+	}
+
+	entry, err := box.add(ctx, c, ev, quorum)
+	if err != nil {
+		seenRepos.Unreserve(ctx, c.Key)
+		return "", err
+	}
+
+	return ev.ID, box.deliver(ctx, entry)
+}
+
+// publishedSince reports whether the repo of the key was published, or blocked, after the given time,
+// i.e. the note queued for it then was already sent, or isn't wanted anymore.
+func publishedSince(ctx context.Context, seenRepos *pipeline.Seen, key string, since time.Time) (bool, error) {
+	records, err := seenRepos.Records(ctx, []string{key})
+	if err != nil {
+		return false, err
+	}
+
+	value, ok := records[key]
+	if !ok {
+		return false, nil
+	}
+
+	record, ok := seen.ParseRecord(value)
+	if !ok {
+		// Repos seen before records were introduced were published long ago.
+		return false, nil
+	}
+
+	return record.Blocked || record.PublishedAt.After(since), nil
+}
+
+// ResumePaced publishes the notes left in the paced queue of the channel,
+// e.g. after the run that queued them was stopped. It holds the lock of the channel meanwhile.
+// seenURI is the store the repos are tracked in, it defaults to the Redis URI.
+func ResumePaced(ctx context.Context, sk, redisURI, seenURI, channel string, quorum int, lockOpts RunLockOptions) error {
+	if channel == "" {
+		channel = defaultChannel
+	}
+
+	rdb, err := newRedisClient(ctx, redisURI)
+	if err != nil {
+		return err
+	}
+	defer rdb.Close()
+
+	var runLock *lock.Lock
+	if !lockOpts.Disabled {
+		if runLock, err = acquireRunLock(ctx, rdb, channel, lockOpts); err != nil {
+			return err
+		}
+		defer runLock.Release(context.Background())
+
+		// The run stops as soon as the lock is lost.
+		var stop context.CancelFunc
+		ctx, stop = runLock.Keep(ctx)
+		defer stop()
+	}
+
+	store, err := openSeenStore(rdb, seenURI)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	seenRepos := keyedSeen(store)
+	box := &outbox{rdb: rdb, seen: seenRepos, events: &eventStore{rdb: rdb}, lock: runLock}
+	queue := &pacedQueue{rdb: rdb, channel: channel}
+
+	n, err := queue.Len(ctx)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("%d notes left in the paced queue of channel %s", n, channel)
+
+	return drainPaced(ctx, sk, queue, seenRepos, box, newRelayLimiter(), PublishOptions{Quorum: quorum}.quorum(), runLock)
+}
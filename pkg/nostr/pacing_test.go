@@ -0,0 +1,202 @@
+package nostr
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Arturomtz8/github-inspector/pkg/pipeline"
+	"github.com/Arturomtz8/github-inspector/pkg/seen"
+)
+
+func TestParsePacingWindow(t *testing.T) {
+	tests := []struct {
+		window  string
+		want    PacingOptions
+		wantErr bool
+	}{
+		{window: "16:00-22:00", want: PacingOptions{Start: 16 * time.Hour, End: 22 * time.Hour}},
+		{window: "22:30 - 02:00", want: PacingOptions{Start: 22*time.Hour + 30*time.Minute, End: 2 * time.Hour}},
+		{window: "16:00", wantErr: true},
+		{window: "16:00-25:00", wantErr: true},
+		{window: "16:00-16:00", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.window, func(t *testing.T) {
+			got, err := ParsePacingWindow(tt.window)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestPacingWindow(t *testing.T) {
+	day := func(d, h, m int) time.Time {
+		return time.Date(2023, time.June, d, h, m, 0, 0, time.UTC)
+	}
+
+	evening := PacingOptions{Start: 16 * time.Hour, End: 22 * time.Hour}
+	night := PacingOptions{Start: 22 * time.Hour, End: 2 * time.Hour}
+
+	tests := []struct {
+		name      string
+		pacing    PacingOptions
+		now       time.Time
+		wantStart time.Time
+		wantEnd   time.Time
+	}{
+		{name: "before the window", pacing: evening, now: day(10, 10, 0), wantStart: day(10, 16, 0), wantEnd: day(10, 22, 0)},
+		{name: "in the window", pacing: evening, now: day(10, 17, 30), wantStart: day(10, 17, 30), wantEnd: day(10, 22, 0)},
+		{name: "after the window", pacing: evening, now: day(10, 23, 0), wantStart: day(11, 16, 0), wantEnd: day(11, 22, 0)},
+		{name: "crossing midnight, before", pacing: night, now: day(10, 12, 0), wantStart: day(10, 22, 0), wantEnd: day(11, 2, 0)},
+		{name: "crossing midnight, before midnight", pacing: night, now: day(10, 23, 0), wantStart: day(10, 23, 0), wantEnd: day(11, 2, 0)},
+		{name: "crossing midnight, after midnight", pacing: night, now: day(10, 1, 0), wantStart: day(10, 1, 0), wantEnd: day(10, 2, 0)},
+		{name: "crossing midnight, after", pacing: night, now: day(10, 3, 0), wantStart: day(10, 22, 0), wantEnd: day(11, 2, 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end := tt.pacing.window(tt.now)
+			require.Equal(t, tt.wantStart, start)
+			require.Equal(t, tt.wantEnd, end)
+		})
+	}
+}
+
+func TestPacingWindowLocation(t *testing.T) {
+	loc := time.FixedZone("UTC-6", -6*60*60)
+	pacing := PacingOptions{Start: 16 * time.Hour, End: 22 * time.Hour, Location: loc}
+
+	start, end := pacing.window(time.Date(2023, time.June, 10, 12, 0, 0, 0, time.UTC))
+	require.True(t, start.Equal(time.Date(2023, time.June, 10, 22, 0, 0, 0, time.UTC)))
+	require.True(t, end.Equal(time.Date(2023, time.June, 11, 4, 0, 0, 0, time.UTC)))
+}
+
+func TestPacingSlots(t *testing.T) {
+	now := time.Date(2023, time.June, 10, 10, 0, 0, 0, time.UTC)
+	pacing := PacingOptions{Start: 16 * time.Hour, End: 22 * time.Hour}
+
+	require.Empty(t, pacing.slots(now, 0))
+
+	require.Equal(t, []time.Time{
+		time.Date(2023, time.June, 10, 16, 0, 0, 0, time.UTC),
+		time.Date(2023, time.June, 10, 18, 0, 0, 0, time.UTC),
+		time.Date(2023, time.June, 10, 20, 0, 0, 0, time.UTC),
+	}, pacing.slots(now, 3))
+
+	pacing.Jitter = true
+	for i, slot := range pacing.slots(now, 3) {
+		share := time.Date(2023, time.June, 10, 16+2*i, 0, 0, 0, time.UTC)
+		require.False(t, slot.Before(share), "slot %d is before its share of the window", i)
+		require.True(t, slot.Before(share.Add(2*time.Hour)), "slot %d is after its share of the window", i)
+	}
+}
+
+func TestPacedQueue(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping short mode")
+	}
+
+	ctx := context.Background()
+
+	rdb, err := newRedisClient(ctx, os.Getenv("REDIS_URI"))
+	require.NoError(t, err)
+	defer rdb.Close()
+
+	queue := &pacedQueue{rdb: rdb, channel: "test"}
+	require.NoError(t, rdb.Del(ctx, queue.notesKey(), queue.dueKey()).Err())
+	defer rdb.Del(ctx, queue.notesKey(), queue.dueKey())
+
+	now := time.Now().Truncate(time.Millisecond)
+	first := pacedNote{Repo: "foo/bar", SeenKey: "seen/foo/bar", Content: "first note", Due: now.Add(time.Hour)}
+	second := pacedNote{Repo: "foo/baz", SeenKey: "seen/foo/baz", Content: "second note", Due: now}
+
+	added, err := queue.Add(ctx, first)
+	require.NoError(t, err)
+	require.True(t, added)
+
+	added, err = queue.Add(ctx, second)
+	require.NoError(t, err)
+	require.True(t, added)
+
+	added, err = queue.Add(ctx, pacedNote{Repo: "foo/bar", SeenKey: "seen/foo/bar", Due: now})
+	require.NoError(t, err)
+	require.False(t, added, "a repo is queued once")
+
+	n, err := queue.Len(ctx)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, n)
+
+	// the note due first comes first.
+	next, err := queue.Next(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "second note", next.Content)
+	require.True(t, next.Due.Equal(now))
+
+	// a postponed note falls due after the others.
+	require.NoError(t, queue.Postpone(ctx, next, now.Add(2*time.Hour)))
+
+	next, err = queue.Next(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "first note", next.Content)
+
+	require.NoError(t, queue.Remove(ctx, second.SeenKey))
+
+	// a note gone from the hash is skipped.
+	require.NoError(t, rdb.HDel(ctx, queue.notesKey(), first.SeenKey).Err())
+
+	next, err = queue.Next(ctx)
+	require.NoError(t, err)
+	require.Nil(t, next)
+
+	n, err = queue.Len(ctx)
+	require.NoError(t, err)
+	require.Zero(t, n)
+
+	// the replies of a thread are chained in the order they are sent.
+	note := &pacedNote{Repo: "foo/bar", Root: "root-id", Author: "pub"}
+	defer rdb.Del(ctx, pacedThreadKeyPrefix+note.Root)
+
+	th, err := queue.thread(ctx, note)
+	require.NoError(t, err)
+	require.Equal(t, nostr.Tags{{"e", "root-id", "", "root"}, {"p", "pub"}}, th.replyTags())
+
+	require.NoError(t, queue.replied(ctx, th, "first-reply"))
+
+	th, err = queue.thread(ctx, note)
+	require.NoError(t, err)
+	require.Equal(t, "first-reply", th.last)
+
+	th, err = queue.thread(ctx, &pacedNote{Repo: "foo/baz"})
+	require.NoError(t, err)
+	require.Nil(t, th, "the note isn't part of a thread")
+}
+
+func TestSendPacedPublished(t *testing.T) {
+	ctx := context.Background()
+	seenRepos := pipeline.NewSeen(seen.NewMemoryStore(), seen.Namespace{Sink: "nostr", Channel: "test", Language: "Go"})
+
+	note := &pacedNote{Repo: "foo/bar", SeenKey: seenRepos.Key("foo/bar"), QueuedAt: time.Now().Add(-time.Hour)}
+
+	// the run that queued the note published it and stopped before removing it from the queue.
+	require.NoError(t, seenRepos.Commit(ctx, note.SeenKey, seenRepos.Token(), seen.Record{PublishedAt: time.Now()}))
+
+	// the note is dropped without reaching the outbox.
+	done, err := sendPaced(ctx, "", nil, seenRepos, nil, 1, note)
+	require.NoError(t, err)
+	require.True(t, done)
+
+	free, err := seenRepos.ReserveMany(ctx, []string{note.SeenKey})
+	require.NoError(t, err)
+	require.True(t, free[0], "the repo wasn't reserved")
+}
@@ -0,0 +1,166 @@
+package nostr
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"golang.org/x/time/rate"
+
+	"github.com/Arturomtz8/github-inspector/pkg/pipeline"
+	"github.com/Arturomtz8/github-inspector/pkg/templates"
+)
+
+// publisher is the Nostr sink of the pipeline: it sends the posts as notes through the outbox,
+// queues them for review or pacing, or prints them in a dry run.
+type publisher struct {
+	sk       string
+	language string
+	tmpls    *templates.Set
+	// limiter paces the root of the thread, it's the limiter of the sink.
+	limiter  *rate.Limiter
+	threaded bool
+	seen     *pipeline.Seen
+	box      *outbox
+	quorum   int
+	// dry prints the notes instead of publishing them, if set.
+	dry *dryRun
+	// review queues the notes for an editor, if set.
+	review *reviewQueue
+	// paced queues the notes until they fall due, if set.
+	paced *pacedQueue
+	// pacing spreads the notes over a time window, dry runs only log it.
+	pacing *PacingOptions
+	slots  []time.Time
+	th     *thread
+}
+
+// Start spreads the notes over the pacing window and posts the root of the thread.
+func (p *publisher) Start(ctx context.Context, candidates []*pipeline.Candidate) error {
+	if p.pacing != nil {
+		p.slots = p.pacing.slots(time.Now(), len(candidates))
+	}
+
+	if !p.threaded || p.review != nil {
+		return nil
+	}
+
+	if err := p.limiter.Wait(ctx); err != nil {
+		return err
+	}
+
+	root, err := buildNote(p.sk, threadRootContent(p.language, p.tmpls.Languages().Hashtags(p.language)), nil)
+	if err != nil {
+		return err
+	}
+
+	if err := p.send(ctx, nil, root); err != nil {
+		if p.dry != nil {
+			return err
+		}
+
+		// The replies go on once a relay has the root, the outbox delivers it to the others.
+		// Otherwise the root is dropped from the outbox, so a later flush doesn't post
+		// an empty thread next to the one of the next run.
+		delivered, derr := p.box.delivered(ctx, root.ID)
+		if derr != nil || !delivered {
+			if err := p.box.drop(ctx, root.ID); err != nil {
+				log.Printf("error occurred dropping the root of the thread %s: %v", root.ID, err)
+			}
+
+			return err
+		}
+
+		log.Printf("the root of the thread %s reached some relays, posting the replies: %v", root.ID, err)
+	}
+
+	p.th = &thread{pub: root.PubKey, root: root.ID}
+
+	return nil
+}
+
+// Publish sends the note of the post, the repo is committed by the outbox
+// once a quorum of relays acknowledged it.
+func (p *publisher) Publish(ctx context.Context, post *pipeline.Post) error {
+	c := post.Candidate
+	repo := c.Repo
+
+	if p.review != nil {
+		if queued, err := p.review.Add(ctx, c, post.Content); err != nil {
+			log.Printf("error occurred queueing repo for review: %v", err)
+		} else if !queued {
+			log.Printf("%s is already waiting for review", repo.FullName)
+		}
+
+		// The repo is reserved again when its note is approved and published.
+		p.seen.Unreserve(ctx, c.Key)
+		return nil
+	}
+
+	if p.paced != nil {
+		note := pacedNote{
+			Repo:      repo.FullName,
+			SeenKey:   c.Key,
+			RepoURL:   repo.HtmlURL,
+			RepoStars: repo.StargazersCount,
+			Content:   post.Content,
+			Due:       p.slots[post.Index],
+			QueuedAt:  time.Now(),
+		}
+
+		// The replies are chained when the notes fall due, in the order they are sent.
+		if p.th != nil {
+			note.Root, note.Author = p.th.root, p.th.pub
+		}
+
+		if queued, err := p.paced.Add(ctx, note); err != nil {
+			log.Printf("error occurred queueing repo: %v", err)
+		} else if !queued {
+			log.Printf("%s is already queued", repo.FullName)
+		} else {
+			log.Printf("%s is due at %s", repo.FullName, note.Due.Format(time.RFC3339))
+		}
+
+		// The repo is reserved again when its note falls due.
+		p.seen.Unreserve(ctx, c.Key)
+		return nil
+	}
+
+	if p.dry != nil && p.slots != nil {
+		log.Printf("%s would be due at %s", repo.FullName, p.slots[post.Index].Format(time.RFC3339))
+	}
+
+	var tags nostr.Tags
+	if p.th != nil {
+		tags = p.th.replyTags()
+	}
+
+	ev, err := buildNote(p.sk, post.Content, tags)
+	if err != nil {
+		p.seen.Unreserve(ctx, c.Key)
+		return fmt.Errorf("building note: %w", err)
+	}
+
+	// The outbox keeps retrying the relays that failed,
+	// the repo is committed as seen once a quorum of relays acknowledged it.
+	if err := p.send(ctx, c, ev); err != nil {
+		return err
+	}
+
+	if p.th != nil {
+		p.th.last = ev.ID
+	}
+
+	return nil
+}
+
+// send hands the event over to the outbox, or prints it in a dry run.
+func (p *publisher) send(ctx context.Context, c *pipeline.Candidate, ev nostr.Event) error {
+	if p.dry != nil {
+		return p.dry.print(ev)
+	}
+
+	return p.box.Send(ctx, c, ev, p.quorum)
+}
@@ -0,0 +1,366 @@
+package nostr
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/Arturomtz8/github-inspector/pkg/github"
+	"github.com/Arturomtz8/github-inspector/pkg/lock"
+	"github.com/Arturomtz8/github-inspector/pkg/pipeline"
+	"github.com/Arturomtz8/github-inspector/pkg/seen"
+)
+
+const (
+	// reviewQueueKeyPrefix prefixes the Redis hash of the notes of a channel waiting for review, by ID.
+	reviewQueueKeyPrefix = "review-queue:" + sinkName + ":"
+	// reviewSeqKeyPrefix prefixes the Redis counter giving the IDs of the notes of a channel.
+	reviewSeqKeyPrefix = "review-seq:" + sinkName + ":"
+)
+
+// ReviewStatus is the state of a note in the review queue.
+type ReviewStatus string
+
+const (
+	// ReviewPending notes wait for an editor.
+	ReviewPending ReviewStatus = "pending"
+	// ReviewApproved notes are sent by the next PublishApproved run.
+	ReviewApproved ReviewStatus = "approved"
+)
+
+// ErrReviewNotFound is returned for the IDs missing in the review queue.
+var ErrReviewNotFound = errors.New("note not found in the review queue")
+
+// ReviewItem is a note waiting in the review queue.
+type ReviewItem struct {
+	ID      int64  `json:"id"`
+	Repo    string `json:"repo"`
+	SeenKey string `json:"seen_key"`
+	RepoURL string `json:"repo_url"`
+	// RepoStars is the star count of the repo when the note was queued.
+	RepoStars int          `json:"repo_stars"`
+	Content   string       `json:"content"`
+	Status    ReviewStatus `json:"status"`
+	QueuedAt  time.Time    `json:"queued_at"`
+}
+
+// candidate returns the candidate the note was rendered for.
+func (i ReviewItem) candidate() *pipeline.Candidate {
+	return &pipeline.Candidate{
+		Repo: &github.RepoTrending{
+			FullName:        i.Repo,
+			HtmlURL:         i.RepoURL,
+			StargazersCount: i.RepoStars,
+		},
+		Key: i.SeenKey,
+	}
+}
+
+// reviewQueue keeps the rendered notes of a channel in Redis until an editor approves or rejects them.
+type reviewQueue struct {
+	rdb     *redis.Client
+	channel string
+}
+
+// newReviewQueue returns the review queue of the channel, defaultChannel if it's empty.
+func newReviewQueue(rdb *redis.Client, channel string) *reviewQueue {
+	if channel == "" {
+		channel = defaultChannel
+	}
+
+	return &reviewQueue{rdb: rdb, channel: channel}
+}
+
+func (q *reviewQueue) queueKey() string {
+	return reviewQueueKeyPrefix + q.channel
+}
+
+func (q *reviewQueue) seqKey() string {
+	return reviewSeqKeyPrefix + q.channel
+}
+
+// Add queues the note of the candidate, unless the repo is already in the queue.
+// It reports whether the note was queued.
+func (q *reviewQueue) Add(ctx context.Context, c *pipeline.Candidate, content string) (bool, error) {
+	items, err := q.List(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	for _, item := range items {
+		if item.SeenKey == c.Key {
+			return false, nil
+		}
+	}
+
+	id, err := q.rdb.Incr(ctx, q.seqKey()).Result()
+	if err != nil {
+		return false, err
+	}
+
+	item := ReviewItem{
+		ID:        id,
+		Repo:      c.Repo.FullName,
+		SeenKey:   c.Key,
+		RepoURL:   c.Repo.HtmlURL,
+		RepoStars: c.Repo.StargazersCount,
+		Content:   content,
+		Status:    ReviewPending,
+		QueuedAt:  time.Now().UTC(),
+	}
+
+	return true, q.save(ctx, item)
+}
+
+// List returns the queued notes, oldest first.
+func (q *reviewQueue) List(ctx context.Context) ([]ReviewItem, error) {
+	values, err := q.rdb.HVals(ctx, q.queueKey()).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]ReviewItem, 0, len(values))
+	for _, value := range values {
+		var item ReviewItem
+		if err := json.Unmarshal([]byte(value), &item); err != nil {
+			return nil, err
+		}
+
+		items = append(items, item)
+	}
+
+	sort.Slice(items, func(i, j int) bool { return items[i].ID < items[j].ID })
+
+	return items, nil
+}
+
+// Get returns the queued note with the given ID.
+func (q *reviewQueue) Get(ctx context.Context, id int64) (ReviewItem, error) {
+	value, err := q.rdb.HGet(ctx, q.queueKey(), strconv.FormatInt(id, 10)).Result()
+	if err == redis.Nil {
+		return ReviewItem{}, ErrReviewNotFound
+	} else if err != nil {
+		return ReviewItem{}, err
+	}
+
+	var item ReviewItem
+	if err := json.Unmarshal([]byte(value), &item); err != nil {
+		return ReviewItem{}, err
+	}
+
+	return item, nil
+}
+
+// Remove drops the note from the queue.
+func (q *reviewQueue) Remove(ctx context.Context, id int64) error {
+	return q.rdb.HDel(ctx, q.queueKey(), strconv.FormatInt(id, 10)).Err()
+}
+
+func (q *reviewQueue) save(ctx context.Context, item ReviewItem) error {
+	b, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+
+	return q.rdb.HSet(ctx, q.queueKey(), strconv.FormatInt(item.ID, 10), b).Err()
+}
+
+// ListReview returns the notes waiting in the review queue of the channel.
+func ListReview(ctx context.Context, redisURI, channel string) ([]ReviewItem, error) {
+	rdb, err := newRedisClient(ctx, redisURI)
+	if err != nil {
+		return nil, err
+	}
+	defer rdb.Close()
+
+	return newReviewQueue(rdb, channel).List(ctx)
+}
+
+// GetReview returns the note with the given ID from the review queue of the channel.
+func GetReview(ctx context.Context, redisURI, channel string, id int64) (ReviewItem, error) {
+	rdb, err := newRedisClient(ctx, redisURI)
+	if err != nil {
+		return ReviewItem{}, err
+	}
+	defer rdb.Close()
+
+	return newReviewQueue(rdb, channel).Get(ctx, id)
+}
+
+// ApproveReview approves the note with the given ID, so the next PublishApproved run sends it.
+// If content isn't empty, it replaces the content of the note.
+func ApproveReview(ctx context.Context, redisURI, channel string, id int64, content string) error {
+	return updateReview(ctx, redisURI, channel, id, func(item *ReviewItem) {
+		item.Status = ReviewApproved
+		if content != "" {
+			item.Content = content
+		}
+	})
+}
+
+// EditReview replaces the content of the note with the given ID, keeping its status.
+func EditReview(ctx context.Context, redisURI, channel string, id int64, content string) error {
+	if content == "" {
+		return errors.New("the content of a note can't be empty")
+	}
+
+	return updateReview(ctx, redisURI, channel, id, func(item *ReviewItem) {
+		item.Content = content
+	})
+}
+
+func updateReview(ctx context.Context, redisURI, channel string, id int64, fn func(*ReviewItem)) error {
+	rdb, err := newRedisClient(ctx, redisURI)
+	if err != nil {
+		return err
+	}
+	defer rdb.Close()
+
+	queue := newReviewQueue(rdb, channel)
+
+	item, err := queue.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	fn(&item)
+
+	return queue.save(ctx, item)
+}
+
+// RejectReview drops the note with the given ID from the review queue.
+// The repo is recorded as published, so it's only proposed again
+// when the republish policy allows it. If block is true, it's never proposed again.
+// seenURI is the store the repos were reserved in, it defaults to the Redis URI.
+func RejectReview(ctx context.Context, redisURI, seenURI, channel string, id int64, block bool) error {
+	rdb, err := newRedisClient(ctx, redisURI)
+	if err != nil {
+		return err
+	}
+	defer rdb.Close()
+
+	store, err := openSeenStore(rdb, seenURI)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	queue := newReviewQueue(rdb, channel)
+
+	item, err := queue.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	record := seen.Record{
+		URL:         item.RepoURL,
+		Stars:       item.RepoStars,
+		PublishedAt: time.Now(),
+		Blocked:     block,
+	}
+
+	ttl := pipeline.RecordTTL
+	if block {
+		ttl = 0
+	}
+
+	if err := store.Commit(ctx, item.SeenKey, record.String(), ttl); err != nil {
+		return err
+	}
+
+	return queue.Remove(ctx, id)
+}
+
+// PublishApproved sends the approved notes of the review queue of the channel through the outbox.
+// The repo of a note counts as published once a quorum of relays acknowledged it.
+// A note stays in the queue until it's handed over to the outbox, unless its repo was published meanwhile.
+// It holds the lock of the channel meanwhile, so it doesn't race a run publishing to it.
+// seenURI is the store the repos are tracked in, it defaults to the Redis URI.
+func PublishApproved(ctx context.Context, sk, redisURI, seenURI, channel string, quorum int, lockOpts RunLockOptions) error {
+	if channel == "" {
+		channel = defaultChannel
+	}
+
+	rdb, err := newRedisClient(ctx, redisURI)
+	if err != nil {
+		return err
+	}
+	defer rdb.Close()
+
+	var runLock *lock.Lock
+	if !lockOpts.Disabled {
+		if runLock, err = acquireRunLock(ctx, rdb, channel, lockOpts); err != nil {
+			return err
+		}
+		defer runLock.Release(context.Background())
+
+		var stop context.CancelFunc
+		ctx, stop = runLock.Keep(ctx)
+		defer stop()
+	}
+
+	store, err := openSeenStore(rdb, seenURI)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	queue := newReviewQueue(rdb, channel)
+
+	items, err := queue.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	seenRepos := keyedSeen(store)
+	box := &outbox{rdb: rdb, seen: seenRepos, events: &eventStore{rdb: rdb}, lock: runLock}
+	limiter := newRelayLimiter()
+	quorum = PublishOptions{Quorum: quorum}.quorum()
+
+	for _, item := range items {
+		if item.Status != ReviewApproved {
+			continue
+		}
+
+		published, err := publishedSince(ctx, seenRepos, item.SeenKey, item.QueuedAt)
+		if err != nil {
+			log.Printf("error occurred checking %s: %v", item.Repo, err)
+			continue
+		}
+
+		if published {
+			log.Printf("%s was published since its note was queued, dropping the note", item.Repo)
+		} else {
+			if err := limiter.Wait(ctx); err != nil {
+				return err
+			}
+
+			if err := checkRunLock(ctx, runLock); err != nil {
+				return err
+			}
+
+			id, err := sendQueued(ctx, sk, seenRepos, box, quorum, item.candidate(), item.Content, nil)
+			if err != nil {
+				// The outbox retries the relays that failed once it has the note.
+				log.Printf("error occurred publishing %s: %v", item.Repo, err)
+			}
+
+			if id == "" {
+				continue
+			}
+		}
+
+		if err := queue.Remove(ctx, item.ID); err != nil {
+			return fmt.Errorf("removing note %d from the review queue: %w", item.ID, err)
+		}
+	}
+
+	return nil
+}
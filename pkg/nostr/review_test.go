@@ -0,0 +1,115 @@
+package nostr
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/Arturomtz8/github-inspector/pkg/github"
+	"github.com/Arturomtz8/github-inspector/pkg/pipeline"
+	"github.com/Arturomtz8/github-inspector/pkg/seen"
+)
+
+func TestReviewItemCandidate(t *testing.T) {
+	item := ReviewItem{
+		Repo:      "foo/bar",
+		SeenKey:   "seen/foo/bar",
+		RepoURL:   "https://github.com/foo/bar",
+		RepoStars: 42,
+	}
+
+	c := item.candidate()
+	require.Equal(t, "seen/foo/bar", c.Key)
+	require.Equal(t, "foo/bar", c.Repo.FullName)
+	require.Equal(t, "https://github.com/foo/bar", c.Repo.HtmlURL)
+	require.Equal(t, 42, c.Repo.StargazersCount)
+}
+
+func TestReviewQueue(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping short mode")
+	}
+
+	ctx := context.Background()
+
+	rdb, err := newRedisClient(ctx, os.Getenv("REDIS_URI"))
+	require.NoError(t, err)
+	defer rdb.Close()
+
+	queue := newReviewQueue(rdb, "test")
+	other := newReviewQueue(rdb, "other")
+
+	require.NoError(t, rdb.Del(ctx, queue.queueKey(), other.queueKey()).Err())
+	defer rdb.Del(ctx, queue.queueKey(), other.queueKey())
+	seenRepos := pipeline.NewSeen(seen.NewMemoryStore(), seen.Namespace{})
+
+	first := &pipeline.Candidate{Repo: &github.RepoTrending{FullName: "foo/bar"}, Key: seenRepos.Key("foo/bar")}
+	second := &pipeline.Candidate{Repo: &github.RepoTrending{FullName: "foo/baz"}, Key: seenRepos.Key("foo/baz")}
+
+	queued, err := queue.Add(ctx, first, "first note")
+	require.NoError(t, err)
+	require.True(t, queued)
+
+	queued, err = queue.Add(ctx, second, "second note")
+	require.NoError(t, err)
+	require.True(t, queued)
+
+	queued, err = queue.Add(ctx, first, "first note again")
+	require.NoError(t, err)
+	require.False(t, queued, "a repo is queued once")
+
+	items, err := queue.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	require.Equal(t, "foo/bar", items[0].Repo)
+	require.Equal(t, "foo/baz", items[1].Repo)
+	require.Equal(t, ReviewPending, items[0].Status)
+
+	// every channel has a queue of its own.
+	items, err = other.List(ctx)
+	require.NoError(t, err)
+	require.Empty(t, items)
+
+	items, err = queue.List(ctx)
+	require.NoError(t, err)
+
+	got, err := queue.Get(ctx, items[1].ID)
+	require.NoError(t, err)
+	require.Equal(t, "second note", got.Content)
+
+	require.NoError(t, queue.Remove(ctx, items[1].ID))
+	_, err = queue.Get(ctx, items[1].ID)
+	require.ErrorIs(t, err, ErrReviewNotFound)
+}
+
+func TestPublishedSince(t *testing.T) {
+	ctx := context.Background()
+	store := seen.NewMemoryStore()
+	seenRepos := pipeline.NewSeen(store, seen.Namespace{Sink: "nostr", Channel: "test", Language: "Go"})
+
+	queuedAt := time.Now().Add(-time.Hour)
+	key := seenRepos.Key("foo/bar")
+
+	published, err := publishedSince(ctx, seenRepos, key, queuedAt)
+	require.NoError(t, err)
+	require.False(t, published)
+
+	// a repo published before the note was queued is republished.
+	require.NoError(t, seenRepos.Commit(ctx, key, seenRepos.Token(), seen.Record{PublishedAt: queuedAt.Add(-time.Hour)}))
+	published, err = publishedSince(ctx, seenRepos, key, queuedAt)
+	require.NoError(t, err)
+	require.False(t, published)
+
+	require.NoError(t, seenRepos.Commit(ctx, key, seenRepos.Token(), seen.Record{PublishedAt: time.Now()}))
+	published, err = publishedSince(ctx, seenRepos, key, queuedAt)
+	require.NoError(t, err)
+	require.True(t, published)
+
+	require.NoError(t, seenRepos.Commit(ctx, key, seenRepos.Token(), seen.Record{PublishedAt: queuedAt.Add(-time.Hour), Blocked: true}))
+	published, err = publishedSince(ctx, seenRepos, key, queuedAt)
+	require.NoError(t, err)
+	require.True(t, published, "a blocked repo is never published")
+}
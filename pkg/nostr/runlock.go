@@ -0,0 +1,69 @@
+package nostr
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/Arturomtz8/github-inspector/pkg/lock"
+)
+
+// runLockPrefix is the prefix of the Redis keys of the publishing locks, one per channel.
+const runLockPrefix = "publish-lock/nostr/"
+
+// RunLockOptions keeps concurrent runs from publishing to the same channel,
+// e.g. when a scheduled run is started again by hand while it's still going.
+type RunLockOptions struct {
+	// Disabled lets concurrent runs publish, only the reservations of the seen store keep them apart.
+	Disabled bool
+	// Lease is how long the lock outlives a run that died, it's renewed while the run goes on.
+	Lease time.Duration
+	// Wait is how long a run waits for the lock before giving up, 0 gives up right away.
+	Wait time.Duration
+}
+
+// DefaultRunLockOptions returns the options used when none are given.
+func DefaultRunLockOptions() RunLockOptions {
+	return RunLockOptions{Lease: 2 * time.Minute}
+}
+
+// acquireRunLock takes the publishing lock of the channel.
+// It returns an error wrapping lock.ErrHeld if another run holds it.
+func acquireRunLock(ctx context.Context, rdb *redis.Client, channel string, opts RunLockOptions) (*lock.Lock, error) {
+	lease := opts.Lease
+	if lease <= 0 {
+		lease = DefaultRunLockOptions().Lease
+	}
+
+	l := lock.New(rdb, runLockPrefix+channel, lease)
+
+	if err := l.Wait(ctx, opts.Wait); err != nil {
+		if err != lock.ErrHeld {
+			return nil, err
+		}
+
+		ttl, _ := l.TTL(ctx)
+		return nil, fmt.Errorf("%w of channel %s, its lease expires in %s", err, channel, ttl.Round(time.Second))
+	}
+
+	log.Printf("publishing to channel %s with fencing token %d", channel, l.Fence())
+
+	return l, nil
+}
+
+// checkRunLock returns an error wrapping lock.ErrLost if another run took the lock over,
+// the run has to stop publishing then. A nil lock is always held.
+func checkRunLock(ctx context.Context, l *lock.Lock) error {
+	if l == nil {
+		return nil
+	}
+
+	if err := l.Check(ctx); err != nil {
+		return fmt.Errorf("stopping the run with fencing token %d: %w", l.Fence(), err)
+	}
+
+	return nil
+}
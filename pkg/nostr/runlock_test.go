@@ -0,0 +1,75 @@
+package nostr
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Arturomtz8/github-inspector/pkg/lock"
+	"github.com/Arturomtz8/github-inspector/pkg/pipeline"
+	"github.com/Arturomtz8/github-inspector/pkg/seen"
+)
+
+func TestRunLock(t *testing.T) {
+	require.NoError(t, checkRunLock(context.Background(), nil), "a nil lock is always held")
+
+	if testing.Short() {
+		t.Skip("skipping short mode")
+	}
+
+	ctx := context.Background()
+
+	rdb, err := newRedisClient(ctx, os.Getenv("REDIS_URI"))
+	require.NoError(t, err)
+	defer rdb.Close()
+
+	key := runLockPrefix + "test"
+	require.NoError(t, rdb.Del(ctx, key, key+":fence").Err())
+	defer rdb.Del(ctx, key, key+":fence")
+
+	first, err := acquireRunLock(ctx, rdb, "test", DefaultRunLockOptions())
+	require.NoError(t, err)
+	require.NoError(t, checkRunLock(ctx, first))
+
+	// another channel has its own lock.
+	other, err := acquireRunLock(ctx, rdb, "other", DefaultRunLockOptions())
+	require.NoError(t, err)
+	require.NoError(t, other.Release(ctx))
+
+	_, err = acquireRunLock(ctx, rdb, "test", DefaultRunLockOptions())
+	require.ErrorIs(t, err, lock.ErrHeld)
+	require.Contains(t, err.Error(), "channel test")
+
+	// the lease of the first run expires and a second run takes over.
+	require.NoError(t, rdb.Del(ctx, key).Err())
+
+	second, err := acquireRunLock(ctx, rdb, "test", DefaultRunLockOptions())
+	require.NoError(t, err)
+	require.ErrorIs(t, checkRunLock(ctx, first), lock.ErrLost)
+	require.NoError(t, checkRunLock(ctx, second))
+
+	// the run that lost the lock can't add its notes to the outbox anymore.
+	ev, err := buildNote(nostr.GeneratePrivateKey(), "a note", nil)
+	require.NoError(t, err)
+	defer rdb.Del(ctx, outboxKey(ev.ID))
+	defer rdb.ZRem(ctx, outboxPendingKey, ev.ID)
+
+	seenRepos := pipeline.NewSeen(seen.NewMemoryStore(), seen.Namespace{})
+
+	stale := &outbox{rdb: rdb, seen: seenRepos, lock: first}
+	_, err = stale.add(ctx, nil, ev, 1)
+	require.ErrorIs(t, err, lock.ErrLost)
+	require.Zero(t, rdb.Exists(ctx, outboxKey(ev.ID)).Val())
+
+	box := &outbox{rdb: rdb, seen: seenRepos, lock: second}
+	_, err = box.add(ctx, nil, ev, 1)
+	require.NoError(t, err)
+
+	entry, err := box.load(ctx, ev.ID)
+	require.NoError(t, err)
+	require.Equal(t, ev.ID, entry.Event.ID)
+	require.NoError(t, rdb.ZScore(ctx, outboxPendingKey, ev.ID).Err())
+}
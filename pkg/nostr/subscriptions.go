@@ -0,0 +1,419 @@
+package nostr
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip04"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/Arturomtz8/github-inspector/pkg/github"
+	"github.com/Arturomtz8/github-inspector/pkg/templates"
+)
+
+// Direct messages are encrypted with NIP-04.
+// NIP-17 needs NIP-44 v2 encryption and gift wraps,
+// which the pinned version of go-nostr doesn't provide yet.
+
+const (
+	// subscribersKey is the Redis set holding the pubkeys with at least one subscription.
+	subscribersKey = "subscribers"
+	// subscriptionKeyPrefix prefixes the Redis hash holding the subscriptions of a pubkey,
+	// the fields are the languages and the values the periods.
+	subscriptionKeyPrefix = "subscription:"
+	// dmHelp is sent back when a direct message can't be understood.
+	dmHelp = `Available commands:
+subscribe <language> [daily|weekly|monthly]
+unsubscribe [language]
+list`
+	// dmInterval is the minimum time between two direct messages handled for the same pubkey.
+	dmInterval = 10 * time.Second
+	// helpInterval is the minimum time between two help messages sent to the same pubkey,
+	// so a bot answering the help with something else doesn't keep both in a loop.
+	helpInterval = time.Hour
+	// maxSubscriptions is the number of languages a pubkey can subscribe to,
+	// every language costs a scrape of the trending page when the subscriptions are sent.
+	maxSubscriptions = 10
+)
+
+// subscribeScript stores a subscription unless the pubkey already has maxSubscriptions
+// of other languages. It returns 0 if there are too many.
+var subscribeScript = redis.NewScript(`
+if redis.call("HEXISTS", KEYS[1], ARGV[1]) == 0 and redis.call("HLEN", KEYS[1]) >= tonumber(ARGV[3]) then
+	return 0
+end
+redis.call("HSET", KEYS[1], ARGV[1], ARGV[2])
+redis.call("SADD", KEYS[2], ARGV[4])
+return 1
+`)
+
+// unsubscribeScript removes a subscription, and the subscriber along with its last one.
+var unsubscribeScript = redis.NewScript(`
+redis.call("HDEL", KEYS[1], ARGV[1])
+if redis.call("HLEN", KEYS[1]) == 0 then
+	redis.call("SREM", KEYS[2], ARGV[2])
+end
+return 1
+`)
+
+// errTooManySubscriptions is returned when a pubkey subscribes to more than maxSubscriptions languages.
+var errTooManySubscriptions = fmt.Errorf("no more than %d subscriptions per pubkey", maxSubscriptions)
+
+// dmCommand is a command parsed from a direct message.
+type dmCommand struct {
+	Name     string
+	Language string
+	Since    string
+}
+
+// parseDMCommand parses messages like "subscribe go daily",
+// "unsubscribe go" or "list".
+func parseDMCommand(content string) (dmCommand, bool) {
+	words := strings.Fields(strings.ToLower(content))
+	if len(words) == 0 {
+		return dmCommand{}, false
+	}
+
+	cmd := dmCommand{Name: words[0]}
+
+	switch cmd.Name {
+	case "subscribe":
+		if len(words) < 2 {
+			return dmCommand{}, false
+		}
+
+		cmd.Language = strings.Trim(words[1], ".,!?")
+		if cmd.Language == "" {
+			return dmCommand{}, false
+		}
+
+		cmd.Since = github.TimeToday
+
+		if len(words) > 2 {
+			since, ok := parseSince(words[2])
+			if !ok {
+				return dmCommand{}, false
+			}
+
+			cmd.Since = since
+		}
+	case "unsubscribe":
+		if len(words) > 1 {
+			cmd.Language = strings.Trim(words[1], ".,!?")
+		}
+	case "list":
+	default:
+		return dmCommand{}, false
+	}
+
+	return cmd, true
+}
+
+// subscriptionStore keeps the language and period preferences of every subscriber in Redis.
+type subscriptionStore struct {
+	rdb *redis.Client
+}
+
+func subscriptionKey(pubkey string) string {
+	return subscriptionKeyPrefix + pubkey
+}
+
+// Subscribe stores the period the pubkey wants to receive the given language with.
+// It returns errTooManySubscriptions if the pubkey already has maxSubscriptions other languages.
+func (s *subscriptionStore) Subscribe(ctx context.Context, pubkey, language, since string) error {
+	keys := []string{subscriptionKey(pubkey), subscribersKey}
+
+	n, err := subscribeScript.Run(ctx, s.rdb, keys, language, since, maxSubscriptions, pubkey).Int()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return errTooManySubscriptions
+	}
+
+	return nil
+}
+
+// Unsubscribe removes the subscription of the pubkey to the language,
+// an empty language removes all of them.
+func (s *subscriptionStore) Unsubscribe(ctx context.Context, pubkey, language string) error {
+	if language == "" {
+		_, err := s.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Del(ctx, subscriptionKey(pubkey))
+			pipe.SRem(ctx, subscribersKey, pubkey)
+			return nil
+		})
+
+		return err
+	}
+
+	// A concurrent Subscribe can't add a language between the check and the removal of the subscriber.
+	return unsubscribeScript.Run(ctx, s.rdb, []string{subscriptionKey(pubkey), subscribersKey}, language, pubkey).Err()
+}
+
+// List returns the subscriptions of the pubkey, language to period.
+func (s *subscriptionStore) List(ctx context.Context, pubkey string) (map[string]string, error) {
+	return s.rdb.HGetAll(ctx, subscriptionKey(pubkey)).Result()
+}
+
+// Subscribers returns every pubkey with at least one subscription.
+func (s *subscriptionStore) Subscribers(ctx context.Context) ([]string, error) {
+	return s.rdb.SMembers(ctx, subscribersKey).Result()
+}
+
+// ListenDMs subscribes to the encrypted direct messages sent to the bot
+// and handles the subscribe, unsubscribe and list commands.
+// Only the languages of the mapping can be subscribed to, nil is the embedded one.
+// It runs until the context is canceled.
+func ListenDMs(ctx context.Context, sk, redisURI string, languages templates.Languages) error {
+	if languages == nil {
+		languages = templates.Default().Languages()
+	}
+
+	pub, err := nostr.GetPublicKey(sk)
+	if err != nil {
+		return err
+	}
+
+	rdb, err := newRedisClient(ctx, redisURI)
+	if err != nil {
+		return err
+	}
+	defer rdb.Close()
+
+	store := &subscriptionStore{rdb: rdb}
+
+	limiter := newRequesterLimiter(dmInterval)
+	helps := newRequesterLimiter(helpInterval)
+
+	since := nostr.Now()
+	filters := nostr.Filters{{
+		Kinds: []int{nostr.KindEncryptedDirectMessage},
+		Tags:  nostr.TagMap{"p": []string{pub}},
+		Since: &since,
+	}}
+
+	pool := nostr.NewSimplePool(ctx)
+	for ev := range pool.SubMany(ctx, relayURLs, filters) {
+		if ev.PubKey == pub {
+			continue
+		}
+
+		if !limiter.Allow(ev.PubKey) {
+			log.Printf("%s is being rate limited, skipping direct message %s", ev.PubKey, ev.ID)
+			continue
+		}
+
+		if err := handleDM(ctx, sk, store, helps, languages, ev); err != nil {
+			// No need to stop listening, just continue to the next one.
+			log.Printf("error occurred handling direct message %s: %v", ev.ID, err)
+			continue
+		}
+	}
+
+	return ctx.Err()
+}
+
+// handleDM decrypts a direct message, runs the command in it and answers the sender.
+// A message that isn't a command gets the help, unless the sender got it
+// less than helpInterval ago, as limited by helps.
+func handleDM(ctx context.Context, sk string, store *subscriptionStore, helps *requesterLimiter, languages templates.Languages, ev *nostr.Event) error {
+	secret, err := nip04.ComputeSharedSecret(ev.PubKey, sk)
+	if err != nil {
+		return err
+	}
+
+	content, err := nip04.Decrypt(ev.Content, secret)
+	if err != nil {
+		return err
+	}
+
+	cmd, ok := parseDMCommand(content)
+	if !ok {
+		if !helps.Allow(ev.PubKey) {
+			log.Printf("%s already got the help, ignoring direct message %s", ev.PubKey, ev.ID)
+			return nil
+		}
+
+		return sendDM(ctx, sk, ev.PubKey, dmHelp)
+	}
+
+	var answer string
+
+	switch cmd.Name {
+	case "subscribe":
+		if !languages.Known(cmd.Language) {
+			answer = fmt.Sprintf("Unknown language %s, the languages are: %s.", cmd.Language, strings.Join(languages.Names(), ", "))
+			break
+		}
+
+		err := store.Subscribe(ctx, ev.PubKey, cmd.Language, cmd.Since)
+		if err == errTooManySubscriptions {
+			answer = fmt.Sprintf("You can't subscribe to more than %d languages, unsubscribe from one first.", maxSubscriptions)
+			break
+		} else if err != nil {
+			return err
+		}
+
+		answer = fmt.Sprintf("Subscribed to the %s trending %s repos.", cmd.Since, cmd.Language)
+	case "unsubscribe":
+		if err := store.Unsubscribe(ctx, ev.PubKey, cmd.Language); err != nil {
+			return err
+		}
+
+		answer = "Unsubscribed from every language."
+		if cmd.Language != "" {
+			answer = fmt.Sprintf("Unsubscribed from the trending %s repos.", cmd.Language)
+		}
+	case "list":
+		subs, err := store.List(ctx, ev.PubKey)
+		if err != nil {
+			return err
+		}
+
+		answer = listContent(subs)
+	}
+
+	return sendDM(ctx, sk, ev.PubKey, answer)
+}
+
+// listContent renders the subscriptions of a pubkey.
+func listContent(subs map[string]string) string {
+	if len(subs) == 0 {
+		return "You have no subscriptions."
+	}
+
+	languages := make([]string, 0, len(subs))
+	for language := range subs {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+
+	var b strings.Builder
+	b.WriteString("Your subscriptions:\n")
+
+	for _, language := range languages {
+		fmt.Fprintf(&b, "%s %s\n", language, subs[language])
+	}
+
+	return b.String()
+}
+
+// SendSubscriptions sends every subscriber the trending repos of their languages.
+// It's meant to run once a day, weekly subscriptions are sent on Mondays
+// and monthly ones on the first day of the month.
+// The languages missing in the mapping are skipped, nil is the embedded one.
+func SendSubscriptions(ctx context.Context, sk, redisURI string, languages templates.Languages) error {
+	if languages == nil {
+		languages = templates.Default().Languages()
+	}
+
+	rdb, err := newRedisClient(ctx, redisURI)
+	if err != nil {
+		return err
+	}
+	defer rdb.Close()
+
+	store := &subscriptionStore{rdb: rdb}
+
+	subscribers, err := store.Subscribers(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+
+	// Subscribers share languages, so every trending list is scraped only once.
+	trending := make(map[trendingRequest]*github.TrendingSearchResult)
+
+	for _, subscriber := range subscribers {
+		subs, err := store.List(ctx, subscriber)
+		if err != nil {
+			log.Printf("error occurred getting subscriptions of %s: %v", subscriber, err)
+			continue
+		}
+
+		for language, since := range subs {
+			if !isDue(since, now) {
+				continue
+			}
+
+			if !languages.Known(language) {
+				log.Printf("%s subscribed to unknown language %q, skipping it", subscriber, language)
+				continue
+			}
+
+			req := trendingRequest{Language: language, Since: since}
+
+			repos, ok := trending[req]
+			if !ok {
+				repos, err = github.GetTrendingRepos(since, language)
+				if err != nil {
+					log.Printf("error occurred getting trending %s repos: %v", language, err)
+					continue
+				}
+
+				trending[req] = repos
+			}
+
+			if err := sendDM(ctx, sk, subscriber, replyContent(req, repos)); err != nil {
+				// No need to break loop, just continue to the next one.
+				log.Printf("error occurred sending subscription to %s: %v", subscriber, err)
+				continue
+			}
+		}
+	}
+
+	return nil
+}
+
+// isDue reports whether a subscription with the given period has to be sent on the given day.
+func isDue(since string, day time.Time) bool {
+	switch since {
+	case github.TimeWeek:
+		return day.Weekday() == time.Monday
+	case github.TimeMonth:
+		return day.Day() == 1
+	default:
+		return true
+	}
+}
+
+// sendDM publishes a NIP-04 encrypted direct message to the given pubkey.
+func sendDM(ctx context.Context, sk, pubkey, content string) error {
+	pub, err := nostr.GetPublicKey(sk)
+	if err != nil {
+		return err
+	}
+
+	secret, err := nip04.ComputeSharedSecret(pubkey, sk)
+	if err != nil {
+		return err
+	}
+
+	encrypted, err := nip04.Encrypt(content, secret)
+	if err != nil {
+		return err
+	}
+
+	ev := nostr.Event{
+		PubKey:    pub,
+		CreatedAt: nostr.Now(),
+		Kind:      nostr.KindEncryptedDirectMessage,
+		Tags:      nostr.Tags{{"p", pubkey}},
+		Content:   encrypted,
+	}
+
+	if err := ev.Sign(sk); err != nil {
+		return err
+	}
+
+	_, err = publishEvent(ctx, ev)
+	return err
+}
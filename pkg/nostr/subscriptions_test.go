@@ -0,0 +1,182 @@
+package nostr
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip04"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Arturomtz8/github-inspector/pkg/github"
+)
+
+func TestParseDMCommand(t *testing.T) {
+	type expected struct {
+		output dmCommand
+		ok     bool
+	}
+	var tests = []struct {
+		name     string
+		input    string
+		expected expected
+	}{
+		{
+			name:  "subscribe with period",
+			input: "subscribe go weekly",
+			expected: expected{
+				output: dmCommand{Name: "subscribe", Language: "go", Since: github.TimeWeek},
+				ok:     true,
+			},
+		},
+		{
+			name:  "subscribe without period",
+			input: "Subscribe Rust",
+			expected: expected{
+				output: dmCommand{Name: "subscribe", Language: "rust", Since: github.TimeToday},
+				ok:     true,
+			},
+		},
+		{
+			name:  "subscribe with wrong period",
+			input: "subscribe go yearly",
+		},
+		{
+			name:  "subscribe with punctuation",
+			input: "subscribe go.",
+			expected: expected{
+				output: dmCommand{Name: "subscribe", Language: "go", Since: github.TimeToday},
+				ok:     true,
+			},
+		},
+		{
+			name:  "subscribe to punctuation",
+			input: "subscribe ...",
+		},
+		{
+			name:  "subscribe without language",
+			input: "subscribe",
+		},
+		{
+			name:  "unsubscribe from a language",
+			input: "unsubscribe go",
+			expected: expected{
+				output: dmCommand{Name: "unsubscribe", Language: "go"},
+				ok:     true,
+			},
+		},
+		{
+			name:  "unsubscribe from everything",
+			input: "unsubscribe",
+			expected: expected{
+				output: dmCommand{Name: "unsubscribe"},
+				ok:     true,
+			},
+		},
+		{
+			name:  "list",
+			input: "list",
+			expected: expected{
+				output: dmCommand{Name: "list"},
+				ok:     true,
+			},
+		},
+		{
+			name:  "unknown command",
+			input: "hello",
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := parseDMCommand(tc.input)
+			require.Equal(t, tc.expected.ok, ok)
+			require.Equal(t, tc.expected.output, got)
+		})
+	}
+}
+
+func TestIsDue(t *testing.T) {
+	monday := time.Date(2023, time.October, 2, 16, 0, 0, 0, time.UTC)
+	tuesday := monday.AddDate(0, 0, 1)
+	firstOfNovember := time.Date(2023, time.November, 1, 16, 0, 0, 0, time.UTC)
+
+	require.True(t, isDue(github.TimeToday, tuesday))
+	require.True(t, isDue(github.TimeWeek, monday))
+	require.False(t, isDue(github.TimeWeek, tuesday))
+	require.True(t, isDue(github.TimeMonth, firstOfNovember))
+	require.False(t, isDue(github.TimeMonth, monday))
+}
+
+func TestSubscriptionStore(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping short mode")
+	}
+
+	ctx := context.Background()
+
+	rdb, err := newRedisClient(context.Background(), os.Getenv("REDIS_URI"))
+	require.NoError(t, err)
+	defer rdb.Close()
+
+	store := &subscriptionStore{rdb: rdb}
+	pubkey := "test-subscriber"
+
+	require.NoError(t, store.Subscribe(ctx, pubkey, "go", github.TimeToday))
+	require.NoError(t, store.Subscribe(ctx, pubkey, "rust", github.TimeWeek))
+
+	subs, err := store.List(ctx, pubkey)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"go": github.TimeToday, "rust": github.TimeWeek}, subs)
+
+	subscribers, err := store.Subscribers(ctx)
+	require.NoError(t, err)
+	require.Contains(t, subscribers, pubkey)
+
+	require.NoError(t, store.Unsubscribe(ctx, pubkey, "go"))
+	subs, err = store.List(ctx, pubkey)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"rust": github.TimeWeek}, subs)
+
+	// removing the last subscription removes the subscriber.
+	require.NoError(t, store.Unsubscribe(ctx, pubkey, "rust"))
+	subscribers, err = store.Subscribers(ctx)
+	require.NoError(t, err)
+	require.NotContains(t, subscribers, pubkey)
+
+	// a pubkey can't subscribe to more than maxSubscriptions languages, but can change their periods.
+	for i := 0; i < maxSubscriptions; i++ {
+		require.NoError(t, store.Subscribe(ctx, pubkey, fmt.Sprintf("lang-%d", i), github.TimeToday))
+	}
+	require.ErrorIs(t, store.Subscribe(ctx, pubkey, "go", github.TimeToday), errTooManySubscriptions)
+	require.NoError(t, store.Subscribe(ctx, pubkey, "lang-0", github.TimeWeek))
+	require.NoError(t, rdb.Del(ctx, subscriptionKey(pubkey)).Err())
+	require.NoError(t, rdb.SRem(ctx, subscribersKey, pubkey).Err())
+}
+
+func TestHandleDMHelp(t *testing.T) {
+	sk := nostr.GeneratePrivateKey()
+	senderSK := nostr.GeneratePrivateKey()
+	sender, err := nostr.GetPublicKey(senderSK)
+	require.NoError(t, err)
+
+	pub, err := nostr.GetPublicKey(sk)
+	require.NoError(t, err)
+
+	secret, err := nip04.ComputeSharedSecret(pub, senderSK)
+	require.NoError(t, err)
+
+	content, err := nip04.Encrypt("hello", secret)
+	require.NoError(t, err)
+
+	helps := newRequesterLimiter(helpInterval)
+	require.True(t, helps.Allow(sender))
+
+	// the sender already got the help, the message is ignored without reaching the relays.
+	ev := &nostr.Event{ID: "dm", PubKey: sender, Content: content}
+	require.NoError(t, handleDM(context.Background(), sk, nil, helps, nil, ev))
+}
@@ -0,0 +1,46 @@
+package nostr
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// thread keeps track of the notes published during a threaded run,
+// so every repo can be posted as a NIP-10 reply.
+type thread struct {
+	// pub is the public key of the author of the thread.
+	pub string
+	// root is the ID of the note that opens the thread.
+	root string
+	// last is the ID of the last note published in the thread.
+	last string
+}
+
+// threadRootContent returns the content of the note that opens the thread.
+func threadRootContent(language string, hashtags []string) string {
+	tags := make([]string, len(hashtags))
+	for i, hashtag := range hashtags {
+		tags[i] = "#" + hashtag
+	}
+
+	return fmt.Sprintf("Today's trending %s repos 🧵\n%s", language, strings.Join(tags, " "))
+}
+
+// replyTags returns the tags of the next reply in the thread.
+// The first reply only points to the root, the following ones
+// also point to the previous reply, as NIP-10 marked "e" tags describe.
+func (t *thread) replyTags() nostr.Tags {
+	tags := nostr.Tags{
+		{"e", t.root, "", "root"},
+	}
+
+	if t.last != "" && t.last != t.root {
+		tags = append(tags, nostr.Tag{"e", t.last, "", "reply"})
+	}
+
+	tags = append(tags, nostr.Tag{"p", t.pub})
+
+	return tags
+}
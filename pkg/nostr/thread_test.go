@@ -0,0 +1,33 @@
+package nostr
+
+import (
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip10"
+	"github.com/stretchr/testify/require"
+)
+
+func TestThreadReplyTags(t *testing.T) {
+	th := &thread{pub: "pub", root: "root-id"}
+
+	// the first reply points to the root only.
+	tags := th.replyTags()
+	require.Equal(t, nostr.Tags{
+		{"e", "root-id", "", "root"},
+		{"p", "pub"},
+	}, tags)
+	require.Equal(t, "root-id", nip10.GetThreadRoot(tags).Value())
+	require.Equal(t, "root-id", nip10.GetImmediateReply(tags).Value())
+
+	// the following replies point to the previous one as well.
+	th.last = "first-reply-id"
+	tags = th.replyTags()
+	require.Equal(t, nostr.Tags{
+		{"e", "root-id", "", "root"},
+		{"e", "first-reply-id", "", "reply"},
+		{"p", "pub"},
+	}, tags)
+	require.Equal(t, "root-id", nip10.GetThreadRoot(tags).Value())
+	require.Equal(t, "first-reply-id", nip10.GetImmediateReply(tags).Value())
+}
@@ -0,0 +1,102 @@
+package pipeline
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Arturomtz8/github-inspector/pkg/github"
+	"github.com/Arturomtz8/github-inspector/pkg/seen"
+)
+
+// Dedupe returns the repos that can be published,
+// reserving them for this run. A repo can be published if it was never published
+// or if it's eligible again according to the policy.
+// A reserved repo is skipped by any other run until it's committed or released.
+// The repos are reserved and looked up in batches, not one round trip per repo.
+func Dedupe(ctx context.Context, seenRepos *Seen, policy RepublishPolicy, repos []*github.RepoTrending) ([]*Candidate, error) {
+	// The trending list can hold the same repo more than once.
+	var unique []*github.RepoTrending
+	var keys []string
+
+	known := make(map[string]bool, len(repos))
+	for _, repo := range repos {
+		if known[repo.FullName] {
+			continue
+		}
+
+		known[repo.FullName] = true
+		unique = append(unique, repo)
+		keys = append(keys, seenRepos.Key(repo.FullName))
+	}
+
+	reserved, err := seenRepos.ReserveMany(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	var reservedKeys []string
+	for i, key := range keys {
+		if reserved[i] {
+			reservedKeys = append(reservedKeys, key)
+		}
+	}
+
+	// The records are read once the repos are reserved,
+	// so they can't change until this run commits or releases the repos.
+	records, err := seenRepos.Records(ctx, reservedKeys)
+	if err != nil {
+		for _, key := range reservedKeys {
+			seenRepos.Unreserve(ctx, key)
+		}
+
+		return nil, err
+	}
+
+	var candidates []*Candidate
+
+	now := time.Now()
+
+	for i, repo := range unique {
+		key := keys[i]
+
+		if !reserved[i] {
+			log.Printf("%s is being published by another run and can safely be skipped", repo.FullName)
+			continue
+		}
+
+		value, ok := records[key]
+		if !ok {
+			log.Printf("%s is not seen", repo.FullName)
+
+			// the repos is not seen, so the repo can be published.
+			candidates = append(candidates, &Candidate{Repo: repo, Key: key})
+			continue
+		}
+
+		previous, ok := seen.ParseRecord(value)
+		if !ok {
+			log.Printf("%s is seen and can safely be skipped", repo.FullName)
+			seenRepos.Unreserve(ctx, key)
+			continue
+		}
+
+		eligible, milestone := policy.eligible(previous, repo.StargazersCount, now)
+		if !eligible {
+			log.Printf("%s is seen and can safely be skipped", repo.FullName)
+			seenRepos.Unreserve(ctx, key)
+			continue
+		}
+
+		log.Printf("%s is seen, but it went from %d to %d stars", repo.FullName, previous.Stars, repo.StargazersCount)
+
+		candidates = append(candidates, &Candidate{
+			Repo:      repo,
+			Key:       key,
+			Previous:  &previous,
+			Milestone: milestone,
+		})
+	}
+
+	return candidates, nil
+}
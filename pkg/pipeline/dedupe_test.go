@@ -0,0 +1,129 @@
+package pipeline
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/Arturomtz8/github-inspector/pkg/github"
+	"github.com/Arturomtz8/github-inspector/pkg/seen"
+)
+
+func TestDedupe(t *testing.T) {
+	ctx := context.Background()
+
+	store := seen.NewMemoryStore()
+
+	seenRepos := NewSeen(store, seen.Namespace{Sink: "nostr", Channel: "test", Language: "Go"})
+
+	// simulates an scenario where repos can be duplicated.
+	repos := []*github.RepoTrending{
+		{
+			FullName: "danvergara/dblab",
+			HtmlURL:  "https://github.com/danvergara/dblab",
+		},
+		{
+			FullName: "danvergara/dblab",
+			HtmlURL:  "https://github.com/danvergara/dblab",
+		},
+		{
+			FullName: "tofu/tofu",
+			HtmlURL:  "https://github.com/tofu/tofu",
+		},
+		{
+			FullName: "tofu/tofu",
+			HtmlURL:  "https://github.com/tofu/tofu",
+		},
+		{
+			FullName: "argoproj/argo-workflows",
+			HtmlURL:  "https://github.com/argoproj/argo-workflows",
+		},
+		{
+			FullName: "go-resty/resty",
+			HtmlURL:  "https://github.com/go-resty/resty",
+		},
+	}
+
+	policy := DefaultRepublishPolicy()
+
+	filteredRepos, err := Dedupe(ctx, seenRepos, policy, repos)
+
+	require.NoError(t, err)
+	// the lenght of the repos slice should be 4 because there are only 4 unique repos.
+	require.Len(t, filteredRepos, 4)
+
+	// argo and resty get published two days ago with 900 stars.
+	for _, fullName := range []string{"argoproj/argo-workflows", "go-resty/resty"} {
+		err := seenRepos.Commit(ctx, seenRepos.Key(fullName), seenRepos.Token(), seen.Record{
+			URL:         "https://github.com/" + fullName,
+			Stars:       900,
+			PublishedAt: time.Now().Add(-48 * time.Hour),
+		})
+		require.NoError(t, err)
+	}
+
+	repos[4].StargazersCount = 950
+	repos[5].StargazersCount = 950
+
+	filteredRepos, err = Dedupe(ctx, seenRepos, policy, repos)
+	require.NoError(t, err)
+	// the repo should be empty since there's no new repo,
+	// dblab and tofu are still reserved and argo and resty didn't gain enough stars.
+	require.Len(t, filteredRepos, 0)
+
+	// argo and resty cross the 1k milestone.
+	repos[4].StargazersCount = 1001
+	repos[5].StargazersCount = 1001
+
+	filteredRepos, err = Dedupe(ctx, seenRepos, policy, repos)
+	require.NoError(t, err)
+	// should be 2 repos since dblab and tofu are still reserved.
+	require.Len(t, filteredRepos, 2)
+	require.Equal(t, 1000, filteredRepos[0].Milestone)
+	require.Equal(t, 101, filteredRepos[0].Gained())
+
+	// repos seen before records were introduced only hold their URL,
+	// they are skipped until they expire.
+	legacy := []*github.RepoTrending{{FullName: "legacy/repo", StargazersCount: 20000}}
+	require.NoError(t, store.Commit(ctx, seenRepos.Key("legacy/repo"), "https://github.com/legacy/repo", time.Hour))
+
+	// the same repos are not seen by a bot publishing to another channel.
+	other := NewSeen(store, seen.Namespace{Sink: "nostr", Channel: "other", Language: "Go"})
+	filteredRepos, err = Dedupe(ctx, other, policy, repos)
+	require.NoError(t, err)
+	require.Len(t, filteredRepos, 4)
+
+	filteredRepos, err = Dedupe(ctx, seenRepos, policy, legacy)
+	require.NoError(t, err)
+	require.Len(t, filteredRepos, 0)
+}
+
+func TestDedupeReadOnly(t *testing.T) {
+	ctx := context.Background()
+	store := seen.NewMemoryStore()
+	ns := seen.Namespace{Sink: "nostr", Channel: "test", Language: "Go"}
+
+	repos := []*github.RepoTrending{{FullName: "foo/bar"}, {FullName: "foo/baz"}}
+
+	// foo/baz is being published by another run.
+	other := NewSeen(store, ns)
+	_, err := other.ReserveMany(ctx, []string{other.Key("foo/baz")})
+	require.NoError(t, err)
+
+	dry := NewSeen(store, ns)
+	dry.ReadOnly = true
+
+	// a dry run can be repeated, it doesn't reserve the repos.
+	for i := 0; i < 2; i++ {
+		filteredRepos, err := Dedupe(ctx, dry, DefaultRepublishPolicy(), repos)
+		require.NoError(t, err)
+		require.Len(t, filteredRepos, 1)
+		require.Equal(t, "foo/bar", filteredRepos[0].Repo.FullName)
+	}
+
+	_, reserved, err := store.Get(ctx, seen.LockKey(dry.Key("foo/bar")))
+	require.NoError(t, err)
+	require.False(t, reserved)
+}
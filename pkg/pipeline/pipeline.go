@@ -0,0 +1,280 @@
+// Package pipeline runs the trending repos through the stages shared by every destination:
+// source → enrich → filter → rank → dedupe → render → publish.
+// The first stages collect the repos once, the last ones run for every sink,
+// each with its own templates, rate limit and namespace in the seen store.
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"golang.org/x/time/rate"
+
+	"github.com/Arturomtz8/github-inspector/pkg/curation"
+	"github.com/Arturomtz8/github-inspector/pkg/filter"
+	"github.com/Arturomtz8/github-inspector/pkg/github"
+	"github.com/Arturomtz8/github-inspector/pkg/templates"
+)
+
+// Source returns the repos a run starts with.
+type Source func(ctx context.Context) ([]*github.RepoTrending, error)
+
+// Enricher adds to the repos or changes them before they are filtered.
+type Enricher func(ctx context.Context, repos []*github.RepoTrending) ([]*github.RepoTrending, error)
+
+// Ranker orders the repos, the first ones are published first.
+type Ranker func(repos []*github.RepoTrending) []*github.RepoTrending
+
+// Post is the rendered post of a candidate.
+type Post struct {
+	*Candidate
+	// Content is the candidate rendered by the templates of the sink.
+	Content string
+	// Index is the position of the candidate among the candidates of the sink.
+	Index int
+}
+
+// Publisher is a destination the repos are published to, e.g. Nostr.
+type Publisher interface {
+	// Publish publishes the post. The repo of the post is reserved by the run in the seen store of the sink,
+	// the publisher owns the reservation: it commits the repo once it's published, see Seen.Commit,
+	// or releases it if it can't be published.
+	Publish(ctx context.Context, post *Post) error
+}
+
+// Starter is implemented by the publishers that prepare a run, e.g. by posting the root of a thread.
+// Start is called with the candidates before the first post, only if there's any.
+type Starter interface {
+	Start(ctx context.Context, candidates []*Candidate) error
+}
+
+// Sink is a publisher along with its own settings.
+type Sink struct {
+	// Name names the sink in the logs and errors, e.g. "nostr".
+	Name      string
+	Publisher Publisher
+	// Seen keeps track of the repos published by the sink, in its own namespace.
+	Seen *Seen
+	// Templates renders the posts. Defaults to the embedded templates.
+	Templates *templates.Set
+	// Limiter paces the posts. Nil doesn't limit them.
+	Limiter *rate.Limiter
+	// Republish decides when a published repo can be published again.
+	// Defaults to DefaultRepublishPolicy.
+	Republish *RepublishPolicy
+	// Check is called before every post, an error stops the sink
+	// and releases the repos left, e.g. when the lock of the run was lost. Nil never stops it.
+	Check func(ctx context.Context) error
+}
+
+func (s *Sink) templates() *templates.Set {
+	if s.Templates == nil {
+		return templates.Default()
+	}
+
+	return s.Templates
+}
+
+func (s *Sink) limiter() *rate.Limiter {
+	if s.Limiter == nil {
+		return rate.NewLimiter(rate.Inf, 0)
+	}
+
+	return s.Limiter
+}
+
+func (s *Sink) republish() RepublishPolicy {
+	if s.Republish == nil {
+		return DefaultRepublishPolicy()
+	}
+
+	return *s.Republish
+}
+
+func (s *Sink) check(ctx context.Context) error {
+	if s.Check == nil {
+		return nil
+	}
+
+	return s.Check(ctx)
+}
+
+// Pipeline collects the repos and publishes them to its sinks.
+type Pipeline struct {
+	Source    Source
+	Enrichers []Enricher
+	// Filter keeps the repos that fail its rules out. Nil lets every repo through.
+	Filter *filter.Policy
+	// Rank orders the repos. Nil keeps the order of the source.
+	Rank Ranker
+	// Language is the language of the run, given to the repos without a detected one.
+	Language string
+	Sinks    []*Sink
+}
+
+// Run collects the repos and publishes them to every sink.
+// A sink failing doesn't keep the others from publishing, their errors are joined.
+func (p *Pipeline) Run(ctx context.Context) error {
+	repos, err := p.Collect(ctx)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, s := range p.Sinks {
+		if err := p.Deliver(ctx, s, repos); err != nil {
+			errs = append(errs, fmt.Errorf("sink %s: %w", s.Name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Collect returns the repos of the source, enriched, filtered and ranked.
+func (p *Pipeline) Collect(ctx context.Context) ([]*github.RepoTrending, error) {
+	repos, err := p.Source(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, enrich := range p.Enrichers {
+		if repos, err = enrich(ctx, repos); err != nil {
+			return nil, err
+		}
+	}
+
+	kept, rejected := p.Filter.Apply(repos)
+	for _, r := range rejected {
+		log.Printf("%s is filtered out: %s", r.Repo.FullName, r.Reason)
+	}
+
+	if p.Rank != nil {
+		kept = p.Rank(kept)
+	}
+
+	return kept, nil
+}
+
+// Deliver publishes the repos to the sink: the repos it already published are left out,
+// the others are rendered with its templates and handed over to its publisher at its pace.
+func (p *Pipeline) Deliver(ctx context.Context, s *Sink, repos []*github.RepoTrending) error {
+	candidates, err := Dedupe(ctx, s.Seen, s.republish(), repos)
+	if err != nil {
+		return err
+	}
+
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	tmpls := s.templates()
+	limiter := s.limiter()
+
+	if starter, ok := s.Publisher.(Starter); ok {
+		if err := s.check(ctx); err != nil {
+			s.Seen.UnreserveAll(ctx, candidates)
+			return err
+		}
+
+		if err := starter.Start(ctx, candidates); err != nil {
+			s.Seen.UnreserveAll(ctx, candidates)
+			return err
+		}
+	}
+
+	for i, c := range candidates {
+		// Repos without a detected language get the hashtags of the run.
+		if c.Repo.Language == "" {
+			c.Repo.Language = p.Language
+		}
+
+		if err := limiter.Wait(ctx); err != nil {
+			s.Seen.Unreserve(ctx, c.Key)
+			continue
+		}
+
+		content, err := Render(tmpls, c)
+		if err != nil {
+			// No need to break loop, just continue to the next one.
+			log.Printf("error occurred parsing repo into template: %v", err)
+			s.Seen.Unreserve(ctx, c.Key)
+			continue
+		}
+
+		// The posts left are another run's to publish if the check fails.
+		if err := s.check(ctx); err != nil {
+			s.Seen.UnreserveAll(ctx, candidates[i:])
+			return err
+		}
+
+		log.Printf("repo: %s", content)
+
+		if err := s.Publisher.Publish(ctx, &Post{Candidate: c, Content: content, Index: i}); err != nil {
+			// No need to break loop, just continue to the next one.
+			log.Printf("error occurred publishing repo to %s: %v", s.Name, err)
+			continue
+		}
+	}
+
+	return nil
+}
+
+// Trending returns the source of the trending repos of the language over the period since.
+func Trending(since, language string) Source {
+	return func(_ context.Context) ([]*github.RepoTrending, error) {
+		repos, err := github.GetTrendingRepos(since, language)
+		if err != nil {
+			return nil, err
+		}
+
+		return repos.Items, nil
+	}
+}
+
+// Curate returns the enricher merging the curation into the repos, see curation.Merge.
+func Curate(c *curation.Curation, fetch curation.Fetcher) Enricher {
+	return func(_ context.Context, repos []*github.RepoTrending) ([]*github.RepoTrending, error) {
+		return c.Merge(repos, fetch), nil
+	}
+}
+
+// Details returns the enricher fetching the fields of the repos the trending page doesn't show,
+// e.g. archived and fork, when the policy checks them. The repos the policy allows aren't fetched,
+// and the repos that can't be fetched are kept with a warning, the policy checks them
+// with what the trending page shows. The other fields, like the descriptions of the editors, are kept.
+func Details(policy *filter.Policy, fetch curation.Fetcher) Enricher {
+	return func(_ context.Context, repos []*github.RepoTrending) ([]*github.RepoTrending, error) {
+		if !policy.NeedsDetails() {
+			return repos, nil
+		}
+
+		enriched := make([]*github.RepoTrending, 0, len(repos))
+		for _, repo := range repos {
+			if policy.Allows(repo.FullName) {
+				enriched = append(enriched, repo)
+				continue
+			}
+
+			details, err := fetch(repo.FullName)
+			if err != nil {
+				// No need to break loop, just continue to the next one.
+				log.Printf("warning: the details of %s can't be fetched, it's filtered without them: %v", repo.FullName, err)
+				enriched = append(enriched, repo)
+				continue
+			}
+
+			repo.Archived = details.Archived
+			repo.Fork = details.Fork
+			repo.Topics = details.Topics
+			repo.ForksCount = details.ForksCount
+			repo.OpenIssuesCount = details.OpenIssuesCount
+			repo.Size = details.Size
+
+			enriched = append(enriched, repo)
+		}
+
+		return enriched, nil
+	}
+}
@@ -0,0 +1,286 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+
+	"github.com/Arturomtz8/github-inspector/pkg/filter"
+	"github.com/Arturomtz8/github-inspector/pkg/github"
+	"github.com/Arturomtz8/github-inspector/pkg/seen"
+)
+
+// fakePublisher commits every post it's given.
+type fakePublisher struct {
+	seen    *Seen
+	started []*Candidate
+	posts   []*Post
+}
+
+func (p *fakePublisher) Start(_ context.Context, candidates []*Candidate) error {
+	p.started = candidates
+	return nil
+}
+
+func (p *fakePublisher) Publish(ctx context.Context, post *Post) error {
+	p.posts = append(p.posts, post)
+
+	return p.seen.Commit(ctx, post.Key, p.seen.Token(), seen.Record{
+		URL:         post.Repo.HtmlURL,
+		Stars:       post.Repo.StargazersCount,
+		PublishedAt: time.Now(),
+	})
+}
+
+func (p *fakePublisher) published() []string {
+	var names []string
+	for _, post := range p.posts {
+		names = append(names, post.Repo.FullName)
+	}
+
+	return names
+}
+
+func newFakeSink(store seen.Store, name string) (*Sink, *fakePublisher) {
+	seenRepos := NewSeen(store, seen.Namespace{Sink: name, Channel: "test", Language: "Go"})
+	pub := &fakePublisher{seen: seenRepos}
+
+	return &Sink{Name: name, Publisher: pub, Seen: seenRepos}, pub
+}
+
+func testRepos() []*github.RepoTrending {
+	return []*github.RepoTrending{
+		{FullName: "foo/bar", Description: "a good project", StargazersCount: 100},
+		{FullName: "foo/baz", Description: "a crypto project", StargazersCount: 300},
+		{FullName: "foo/qux", Description: "another good project", StargazersCount: 200},
+	}
+}
+
+func TestCollect(t *testing.T) {
+	ctx := context.Background()
+
+	p := &Pipeline{
+		Source: func(context.Context) ([]*github.RepoTrending, error) {
+			return testRepos(), nil
+		},
+		Enrichers: []Enricher{
+			func(_ context.Context, repos []*github.RepoTrending) ([]*github.RepoTrending, error) {
+				return append(repos, &github.RepoTrending{FullName: "pinned/repo", StargazersCount: 50}), nil
+			},
+		},
+		Filter: &filter.Policy{Keywords: []string{"crypto"}},
+		Rank: func(repos []*github.RepoTrending) []*github.RepoTrending {
+			ranked := make([]*github.RepoTrending, 0, len(repos))
+			for i := len(repos) - 1; i >= 0; i-- {
+				ranked = append(ranked, repos[i])
+			}
+
+			return ranked
+		},
+	}
+
+	repos, err := p.Collect(ctx)
+	require.NoError(t, err)
+
+	var names []string
+	for _, repo := range repos {
+		names = append(names, repo.FullName)
+	}
+	require.Equal(t, []string{"pinned/repo", "foo/qux", "foo/bar"}, names)
+
+	p.Source = func(context.Context) ([]*github.RepoTrending, error) {
+		return nil, errors.New("scraping failed")
+	}
+	_, err = p.Collect(ctx)
+	require.Error(t, err)
+}
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+	store := seen.NewMemoryStore()
+
+	first, firstPub := newFakeSink(store, "first")
+	second, secondPub := newFakeSink(store, "second")
+
+	p := &Pipeline{
+		Source: func(context.Context) ([]*github.RepoTrending, error) {
+			return testRepos(), nil
+		},
+		Language: "Go",
+		Sinks:    []*Sink{first, second},
+	}
+
+	// every sink publishes the repos, in its own namespace.
+	require.NoError(t, p.Run(ctx))
+	require.Equal(t, []string{"foo/bar", "foo/baz", "foo/qux"}, firstPub.published())
+	require.Equal(t, []string{"foo/bar", "foo/baz", "foo/qux"}, secondPub.published())
+	require.Len(t, firstPub.started, 3)
+
+	for i, post := range firstPub.posts {
+		require.Equal(t, i, post.Index)
+		require.Equal(t, "Go", post.Repo.Language, "the repos get the language of the run")
+		require.Contains(t, post.Content, post.Repo.FullName)
+	}
+
+	// the repos published by a sink are left out of its next run.
+	third, thirdPub := newFakeSink(store, "third")
+	p.Sinks = []*Sink{first, third}
+	firstPub.posts = nil
+
+	require.NoError(t, p.Run(ctx))
+	require.Empty(t, firstPub.published())
+	require.Len(t, thirdPub.published(), 3)
+}
+
+func TestDeliverCheck(t *testing.T) {
+	ctx := context.Background()
+	store := seen.NewMemoryStore()
+
+	sink, pub := newFakeSink(store, "test")
+
+	// the check fails from the second post on, e.g. when the lock was lost.
+	errLost := errors.New("the lock was lost")
+	checks := 0
+	sink.Check = func(context.Context) error {
+		checks++
+		if checks > 2 {
+			return errLost
+		}
+
+		return nil
+	}
+
+	p := &Pipeline{}
+	err := p.Deliver(ctx, sink, testRepos())
+	require.ErrorIs(t, err, errLost)
+	require.Equal(t, []string{"foo/bar"}, pub.published())
+
+	// the repos left were released, another run can publish them.
+	other, otherPub := newFakeSink(store, "test")
+	require.NoError(t, p.Deliver(ctx, other, testRepos()))
+	require.Equal(t, []string{"foo/baz", "foo/qux"}, otherPub.published())
+}
+
+func TestDetails(t *testing.T) {
+	ctx := context.Background()
+
+	var fetched []string
+	fetch := func(fullName string) (*github.RepoTrending, error) {
+		fetched = append(fetched, fullName)
+
+		switch fullName {
+		case "foo/bar":
+			return &github.RepoTrending{FullName: fullName, Archived: true, Topics: []string{"cli"}}, nil
+		case "foo/baz":
+			return &github.RepoTrending{FullName: fullName, Fork: true}, nil
+		}
+
+		return nil, errors.New("not found")
+	}
+
+	// the repos aren't fetched when the policy doesn't need their details.
+	repos, err := Details(&filter.Policy{MinStars: 10}, fetch)(ctx, testRepos())
+	require.NoError(t, err)
+	require.Len(t, repos, 3)
+	require.Empty(t, fetched)
+
+	policy := &filter.Policy{ExcludeArchived: true, ExcludeForks: true, Allow: []string{"pinned/repo"}}
+	repos = append(testRepos(), &github.RepoTrending{FullName: "pinned/repo"})
+
+	repos, err = Details(policy, fetch)(ctx, repos)
+	require.NoError(t, err)
+	require.Equal(t, []string{"foo/bar", "foo/baz", "foo/qux"}, fetched, "the allowed repos aren't fetched")
+
+	// the repos that can't be fetched are kept, the others keep their description.
+	require.Len(t, repos, 4)
+	require.True(t, repos[0].Archived)
+	require.Equal(t, []string{"cli"}, repos[0].Topics)
+	require.Equal(t, "a good project", repos[0].Description)
+	require.True(t, repos[1].Fork)
+	require.Equal(t, "foo/qux", repos[2].FullName)
+	require.Equal(t, "pinned/repo", repos[3].FullName)
+
+	kept, _ := policy.Apply(repos)
+	require.Len(t, kept, 2)
+	require.Equal(t, "foo/qux", kept[0].FullName)
+	require.Equal(t, "pinned/repo", kept[1].FullName)
+}
+
+func TestCachedFetcher(t *testing.T) {
+	calls := 0
+	fetch := cachedFetcher(func(fullName string) (*github.RepoTrending, error) {
+		calls++
+		if fullName == "foo/missing" {
+			return nil, errors.New("not found")
+		}
+
+		return &github.RepoTrending{FullName: fullName, Description: "fetched"}, nil
+	}, time.Hour)
+
+	repo, err := fetch("foo/bar")
+	require.NoError(t, err)
+	repo.Description = "overridden"
+
+	// the second fetch comes from the cache, without the changes made to the first copy.
+	repo, err = fetch("foo/bar")
+	require.NoError(t, err)
+	require.Equal(t, "fetched", repo.Description)
+	require.Equal(t, 1, calls)
+
+	// the errors aren't cached.
+	_, err = fetch("foo/missing")
+	require.Error(t, err)
+	_, err = fetch("foo/missing")
+	require.Error(t, err)
+	require.Equal(t, 3, calls)
+}
+
+func TestNewTrending(t *testing.T) {
+	_, err := NewTrending(TrendingOptions{}, &Sink{Name: "test"})
+	require.Error(t, err, "a seen store is required")
+
+	limiter := rate.NewLimiter(rate.Every(time.Minute), 1)
+	policy := &filter.Policy{ExcludeForks: true}
+	sink := &Sink{Name: "test", Limiter: limiter}
+
+	run, err := NewTrending(TrendingOptions{Channel: "bots", SeenStore: "memory://", Filter: policy}, sink)
+	require.NoError(t, err)
+	defer run.Close()
+
+	require.Equal(t, "Go", run.Language)
+	require.Equal(t, []*Sink{sink}, run.Sinks)
+	require.Equal(t, seen.Namespace{Sink: "test", Channel: "bots", Language: "Go"}.Key("foo/bar"), sink.Seen.Key("foo/bar"))
+	require.False(t, sink.Seen.ReadOnly)
+	require.Same(t, limiter, sink.Limiter, "the limiter of the sink is kept")
+	require.Len(t, run.Enrichers, 2)
+	require.True(t, run.Filter.NeedsDetails())
+
+	// a dry run only reads the seen store and doesn't wait.
+	sink = &Sink{Name: "test", Limiter: limiter}
+	run, err = NewTrending(TrendingOptions{SeenStore: "memory://", DryRun: true, Language: "Rust"}, sink)
+	require.NoError(t, err)
+	defer run.Close()
+
+	require.Equal(t, seen.Namespace{Sink: "test", Channel: DefaultChannel, Language: "Rust"}.Key("foo/bar"), sink.Seen.Key("foo/bar"))
+	require.True(t, sink.Seen.ReadOnly)
+	require.Equal(t, rate.Inf, sink.Limiter.Limit())
+	require.Equal(t, os.Stdout, TrendingOptions{DryRun: true}.DryRunOutput())
+	require.Nil(t, TrendingOptions{}.DryRunOutput())
+
+	// a given store is used, and left open for its owner.
+	store := seen.NewMemoryStore()
+	sink = &Sink{Name: "test"}
+	run, err = NewTrending(TrendingOptions{Store: store}, sink)
+	require.NoError(t, err)
+	require.NoError(t, sink.Seen.Commit(context.Background(), sink.Seen.Key("foo/bar"), sink.Seen.Token(), seen.Record{}))
+	require.NoError(t, run.Close())
+
+	_, ok, err := store.Get(context.Background(), TrendingOptions{}.Namespace("test").Key("foo/bar"))
+	require.NoError(t, err)
+	require.True(t, ok)
+}
@@ -0,0 +1,31 @@
+package pipeline
+
+import (
+	"github.com/Arturomtz8/github-inspector/pkg/github"
+	"github.com/Arturomtz8/github-inspector/pkg/templates"
+)
+
+// milestoneNote is the data of the milestone template.
+type milestoneNote struct {
+	*github.RepoTrending
+	// Milestone is the star count the repo crossed, 0 if it just gained enough stars.
+	Milestone int
+	// Gained is the number of stars gained since the last publication.
+	Gained int
+	// PreviousStars is the star count of the last publication.
+	PreviousStars int
+}
+
+// Render renders the post of the candidate, repos published again get the milestone template.
+func Render(tmpls *templates.Set, c *Candidate) (string, error) {
+	if c.Previous == nil {
+		return tmpls.Execute(templates.Repo, c.Repo.Language, c.Repo)
+	}
+
+	return tmpls.Execute(templates.Milestone, c.Repo.Language, milestoneNote{
+		RepoTrending:  c.Repo,
+		Milestone:     c.Milestone,
+		Gained:        c.Gained(),
+		PreviousStars: c.Previous.Stars,
+	})
+}
@@ -0,0 +1,54 @@
+package pipeline
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/Arturomtz8/github-inspector/pkg/github"
+	"github.com/Arturomtz8/github-inspector/pkg/seen"
+	"github.com/Arturomtz8/github-inspector/pkg/templates"
+)
+
+func TestRender(t *testing.T) {
+	repo := &github.RepoTrending{
+		FullName:        "foo/bar",
+		HtmlURL:         "https://github.com/foo/bar",
+		Description:     "a good project",
+		Language:        "Go",
+		StargazersCount: 1000,
+		Owner: github.Owner{
+			Login: "foo",
+		},
+	}
+
+	parsedRepo, err := Render(templates.Default(), &Candidate{Repo: repo})
+	t.Log(parsedRepo)
+	require.NoError(t, err)
+	require.NotEmpty(t, parsedRepo)
+}
+
+func TestRenderMilestone(t *testing.T) {
+	c := &Candidate{
+		Repo: &github.RepoTrending{
+			FullName:        "foo/bar",
+			HtmlURL:         "https://github.com/foo/bar",
+			Description:     "a good project",
+			StargazersCount: 5100,
+			Owner: github.Owner{
+				Login: "foo",
+			},
+		},
+		Previous:  &seen.Record{Stars: 4000},
+		Milestone: 5000,
+	}
+
+	parsedRepo, err := Render(templates.Default(), c)
+	require.NoError(t, err)
+	require.Contains(t, parsedRepo, "just reached 5000")
+
+	c.Milestone = 0
+	parsedRepo, err = Render(templates.Default(), c)
+	require.NoError(t, err)
+	require.Contains(t, parsedRepo, "gained 1100")
+}
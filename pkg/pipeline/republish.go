@@ -0,0 +1,92 @@
+package pipeline
+
+import (
+	"time"
+
+	"github.com/Arturomtz8/github-inspector/pkg/github"
+	"github.com/Arturomtz8/github-inspector/pkg/seen"
+)
+
+// RepublishPolicy decides when an already published repo can be published again.
+// A repo is eligible once MinInterval has passed and it either gained enough stars,
+// absolutely or relatively, or crossed one of the milestones.
+type RepublishPolicy struct {
+	// MinInterval is the minimum time between two publications of the same repo.
+	MinInterval time.Duration
+	// MinStarGain is the number of new stars that makes a repo eligible, 0 disables it.
+	MinStarGain int
+	// MinStarGainPercent is the percentage of new stars that makes a repo eligible, 0 disables it.
+	MinStarGainPercent float64
+	// Milestones are the star counts worth a new publication.
+	Milestones []int
+}
+
+// DefaultRepublishPolicy returns the policy used when none is given.
+func DefaultRepublishPolicy() RepublishPolicy {
+	return RepublishPolicy{
+		MinInterval:        SeenTTL,
+		MinStarGain:        1000,
+		MinStarGainPercent: 50,
+		Milestones:         []int{1000, 5000, 10000},
+	}
+}
+
+// Candidate is a repo to be published, along with the reason it's published again, if any.
+type Candidate struct {
+	Repo *github.RepoTrending
+	// Key is the key of the repo in the seen store.
+	Key string
+	// Previous is the last publication of the repo, nil if it was never published.
+	Previous *seen.Record
+	// Milestone is the milestone the repo crossed since its last publication, if any.
+	Milestone int
+}
+
+// Gained returns the stars the repo gained since its last publication.
+func (c *Candidate) Gained() int {
+	if c.Previous == nil {
+		return 0
+	}
+
+	return c.Repo.StargazersCount - c.Previous.Stars
+}
+
+// eligible reports whether the repo can be published again given its previous record.
+// It returns the milestone the repo crossed, 0 if none.
+func (p RepublishPolicy) eligible(previous seen.Record, stars int, now time.Time) (bool, int) {
+	if previous.Blocked {
+		return false, 0
+	}
+
+	if now.Sub(previous.PublishedAt) < p.MinInterval {
+		return false, 0
+	}
+
+	// The highest milestone crossed since the last publication wins.
+	milestone := 0
+	for _, m := range p.Milestones {
+		if previous.Stars < m && stars >= m && m > milestone {
+			milestone = m
+		}
+	}
+
+	if milestone != 0 {
+		return true, milestone
+	}
+
+	gained := stars - previous.Stars
+	if gained <= 0 {
+		return false, 0
+	}
+
+	if p.MinStarGain > 0 && gained >= p.MinStarGain {
+		return true, 0
+	}
+
+	if p.MinStarGainPercent > 0 && previous.Stars > 0 &&
+		float64(gained)*100/float64(previous.Stars) >= p.MinStarGainPercent {
+		return true, 0
+	}
+
+	return false, 0
+}
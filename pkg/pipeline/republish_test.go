@@ -0,0 +1,99 @@
+package pipeline
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/Arturomtz8/github-inspector/pkg/seen"
+)
+
+func TestRepublishPolicyEligible(t *testing.T) {
+	now := time.Date(2023, time.October, 6, 16, 0, 0, 0, time.UTC)
+	policy := DefaultRepublishPolicy()
+
+	type input struct {
+		previous seen.Record
+		stars    int
+	}
+	type expected struct {
+		eligible  bool
+		milestone int
+	}
+	var tests = []struct {
+		name     string
+		input    input
+		expected expected
+	}{
+		{
+			name: "published too recently",
+			input: input{
+				previous: seen.Record{Stars: 100, PublishedAt: now.Add(-time.Hour)},
+				stars:    5000,
+			},
+		},
+		{
+			name: "no new stars",
+			input: input{
+				previous: seen.Record{Stars: 800, PublishedAt: now.Add(-48 * time.Hour)},
+				stars:    800,
+			},
+		},
+		{
+			name: "crossing a milestone",
+			input: input{
+				previous: seen.Record{Stars: 800, PublishedAt: now.Add(-48 * time.Hour)},
+				stars:    1200,
+			},
+			expected: expected{eligible: true, milestone: 1000},
+		},
+		{
+			name: "crossing several milestones keeps the highest",
+			input: input{
+				previous: seen.Record{Stars: 800, PublishedAt: now.Add(-48 * time.Hour)},
+				stars:    6000,
+			},
+			expected: expected{eligible: true, milestone: 5000},
+		},
+		{
+			name: "absolute gain",
+			input: input{
+				previous: seen.Record{Stars: 12000, PublishedAt: now.Add(-48 * time.Hour)},
+				stars:    13000,
+			},
+			expected: expected{eligible: true},
+		},
+		{
+			name: "percentage gain",
+			input: input{
+				previous: seen.Record{Stars: 200, PublishedAt: now.Add(-48 * time.Hour)},
+				stars:    300,
+			},
+			expected: expected{eligible: true},
+		},
+		{
+			name: "blocked",
+			input: input{
+				previous: seen.Record{Stars: 800, PublishedAt: now.Add(-48 * time.Hour), Blocked: true},
+				stars:    6000,
+			},
+		},
+		{
+			name: "not enough gain",
+			input: input{
+				previous: seen.Record{Stars: 2000, PublishedAt: now.Add(-48 * time.Hour)},
+				stars:    2500,
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			eligible, milestone := policy.eligible(tc.input.previous, tc.input.stars, now)
+			require.Equal(t, tc.expected.eligible, eligible)
+			require.Equal(t, tc.expected.milestone, milestone)
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package pipeline
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"time"
+
+	"github.com/Arturomtz8/github-inspector/pkg/seen"
+)
+
+const (
+	// SeenTTL is the minimum time before a published repo can be published again.
+	// 36 hrs.
+	SeenTTL = 36 * time.Hour
+	// RecordTTL is how long the record of a published repo is kept.
+	RecordTTL = 365 * 24 * time.Hour
+	// ReservationLease is how long a repo is reserved while a run publishes it.
+	ReservationLease = 30 * time.Minute
+)
+
+// Seen keeps track of the published repos of a run in two phases:
+// a repo is reserved before being published and committed once it's published,
+// or released if publishing it fails.
+// The record of a published repo is kept under its key in the namespace,
+// the reservation under a separate lock key.
+type Seen struct {
+	store seen.Store
+	ns    seen.Namespace
+	// token identifies the reservations of a run.
+	token string
+	// ReadOnly makes a dry run: repos reserved by another run are still skipped,
+	// but the repos are neither reserved nor released.
+	ReadOnly bool
+}
+
+// NewSeen returns the seen repos of a run in the namespace of a sink.
+func NewSeen(store seen.Store, ns seen.Namespace) *Seen {
+	return &Seen{store: store, ns: ns, token: newReservationToken()}
+}
+
+// Key returns the key of the repo in the namespace of the run.
+func (s *Seen) Key(fullName string) string {
+	return s.ns.Key(fullName)
+}
+
+// Token returns the token identifying the reservations of the run.
+func (s *Seen) Token() string {
+	return s.token
+}
+
+// newReservationToken returns a random token identifying a run.
+func newReservationToken() string {
+	b := make([]byte, 16)
+	// We can safely ignore the error, crypto/rand never fails on supported platforms.
+	rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+// Records returns the values stored under the keys that were published, in a single round trip.
+func (s *Seen) Records(ctx context.Context, keys []string) (map[string]string, error) {
+	return s.store.GetMany(ctx, keys)
+}
+
+// ReserveMany marks the repos of the keys as being published by this run, in a single round trip.
+// It reports false for the repos reserved by another run, in the order of keys.
+func (s *Seen) ReserveMany(ctx context.Context, keys []string) ([]bool, error) {
+	locks := make([]string, len(keys))
+	for i, key := range keys {
+		locks[i] = seen.LockKey(key)
+	}
+
+	if !s.ReadOnly {
+		return s.store.ReserveMany(ctx, locks, s.token, ReservationLease)
+	}
+
+	held, err := s.store.GetMany(ctx, locks)
+	if err != nil {
+		return nil, err
+	}
+
+	free := make([]bool, len(keys))
+	for i, lock := range locks {
+		_, reserved := held[lock]
+		free[i] = !reserved
+	}
+
+	return free, nil
+}
+
+// Commit stores the record of the published repo and drops the reservation made with the given token.
+func (s *Seen) Commit(ctx context.Context, key, token string, record seen.Record) error {
+	if err := s.store.Commit(ctx, key, record.String(), RecordTTL); err != nil {
+		return err
+	}
+
+	return s.store.Release(ctx, seen.LockKey(key), token)
+}
+
+// Release drops the reservation of the repo made with the given token,
+// so a later run can publish it.
+func (s *Seen) Release(ctx context.Context, key, token string) error {
+	return s.store.Release(ctx, seen.LockKey(key), token)
+}
+
+// Extend keeps the reservation made with the given token for d more.
+func (s *Seen) Extend(ctx context.Context, key, token string, d time.Duration) error {
+	return s.store.Extend(ctx, seen.LockKey(key), token, d)
+}
+
+// Unreserve drops the reservation this run made for the repo, logging any error.
+func (s *Seen) Unreserve(ctx context.Context, key string) {
+	if s.ReadOnly {
+		return
+	}
+
+	if err := s.Release(ctx, key, s.token); err != nil {
+		log.Printf("error occurred releasing %s: %v", key, err)
+	}
+}
+
+// UnreserveAll drops the reservations of the candidates.
+func (s *Seen) UnreserveAll(ctx context.Context, candidates []*Candidate) {
+	for _, c := range candidates {
+		s.Unreserve(ctx, c.Key)
+	}
+}
@@ -0,0 +1,213 @@
+package pipeline
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/Arturomtz8/github-inspector/pkg/curation"
+	"github.com/Arturomtz8/github-inspector/pkg/filter"
+	"github.com/Arturomtz8/github-inspector/pkg/github"
+	"github.com/Arturomtz8/github-inspector/pkg/seen"
+	"github.com/Arturomtz8/github-inspector/pkg/templates"
+)
+
+// DefaultChannel is the channel used when none is given.
+const DefaultChannel = "default"
+
+// TrendingOptions changes the way a sink publishes the trending repos, see NewTrending.
+type TrendingOptions struct {
+	// Language is the language of the trending repos. Defaults to Go.
+	Language string
+	// Channel names the account the repos are published with,
+	// it keeps apart the seen repos of several bots sharing a store.
+	// Defaults to DefaultChannel.
+	Channel string
+	// SeenStore is the URI of the store keeping track of the published repos, see seen.Open.
+	SeenStore string
+	// Store is the seen store to use instead of opening SeenStore, the run doesn't close it.
+	Store seen.Store
+	// Limiter paces the posts. Defaults to the limiter of the sink.
+	Limiter *rate.Limiter
+	// Republish decides when a published repo can be published again.
+	// Defaults to DefaultRepublishPolicy.
+	Republish *RepublishPolicy
+	// Templates renders the posts. Defaults to the embedded templates.
+	Templates *templates.Set
+	// Filter keeps the repos that fail its rules out. Nil lets every repo through.
+	Filter *filter.Policy
+	// Curation pins repos and overrides their descriptions and comments. Nil changes nothing.
+	Curation *curation.Curation
+	// DryRun prints the posts instead of publishing them, the seen store is only read.
+	DryRun bool
+	// Output is where a dry run prints the posts. Defaults to os.Stdout.
+	Output io.Writer
+}
+
+func (o TrendingOptions) language() string {
+	if o.Language == "" {
+		return "Go"
+	}
+
+	return o.Language
+}
+
+func (o TrendingOptions) channel() string {
+	if o.Channel == "" {
+		return DefaultChannel
+	}
+
+	return o.Channel
+}
+
+// Namespace returns the namespace of the repos the sink publishes with the options.
+func (o TrendingOptions) Namespace(sink string) seen.Namespace {
+	return seen.Namespace{
+		Sink:     sink,
+		Channel:  o.channel(),
+		Language: o.language(),
+	}
+}
+
+// DryRunOutput returns where a dry run prints the posts, nil if the repos have to be published.
+func (o TrendingOptions) DryRunOutput() io.Writer {
+	if !o.DryRun {
+		return nil
+	}
+
+	if o.Output == nil {
+		return os.Stdout
+	}
+
+	return o.Output
+}
+
+// TrendingRun is a run publishing the trending repos to a sink.
+type TrendingRun struct {
+	*Pipeline
+	store seen.Store
+}
+
+// NewTrending returns the run publishing the trending repos of the language to the sink.
+// It opens the seen store, unless one is given, and sets the seen repos of the sink in its namespace,
+// so its publisher can be given them before the run. Its templates, rate limit
+// and republish policy are the ones of the options, the limiter of the sink is kept if there's none.
+// The run has to be closed once it's over.
+func NewTrending(opts TrendingOptions, sink *Sink) (*TrendingRun, error) {
+	run := &TrendingRun{}
+
+	store := opts.Store
+	if store == nil {
+		if opts.SeenStore == "" {
+			return nil, errors.New("no seen store, set the Redis URI or the seen store")
+		}
+
+		var err error
+		if store, err = seen.Open(opts.SeenStore); err != nil {
+			return nil, err
+		}
+
+		run.store = store
+	}
+
+	language := opts.language()
+
+	sink.Seen = NewSeen(store, opts.Namespace(sink.Name))
+	sink.Seen.ReadOnly = opts.DryRun
+	sink.Templates = opts.Templates
+	sink.Republish = opts.Republish
+
+	if opts.Limiter != nil {
+		sink.Limiter = opts.Limiter
+	}
+
+	if opts.DryRun {
+		// Nothing is published, there's no need to wait.
+		sink.Limiter = rate.NewLimiter(rate.Inf, 0)
+	}
+
+	// The overrides come first, so the filter sees the descriptions of the editors
+	// and lets the pinned repos through.
+	filterPolicy := opts.Filter.Allowing(opts.Curation.Pinned()...)
+
+	run.Pipeline = &Pipeline{
+		Source: Trending(github.TimeToday, language),
+		Enrichers: []Enricher{
+			Curate(opts.Curation, FetchRepo),
+			Details(filterPolicy, FetchRepo),
+		},
+		Filter:   filterPolicy,
+		Language: language,
+		Sinks:    []*Sink{sink},
+	}
+
+	return run, nil
+}
+
+// Close closes the seen store of the run, unless it was given in the options.
+func (r *TrendingRun) Close() error {
+	if r.store == nil {
+		return nil
+	}
+
+	return r.store.Close()
+}
+
+// detailsTTL is how long the repos fetched from the GitHub API are cached,
+// the window of its rate limit.
+const detailsTTL = time.Hour
+
+// fetchRepo fetches the repos from the GitHub API, shared by the runs of the process,
+// so the sinks scheduled under serve don't fetch the same trending repos once each.
+var fetchRepo = cachedFetcher(func(fullName string) (*github.RepoTrending, error) {
+	return github.GetRepositoryByName(github.ReposURL, fullName)
+}, detailsTTL)
+
+// FetchRepo returns the repo with the given full name from the GitHub API,
+// it fetches the pinned repos that aren't trending and the details of the trending ones.
+// The repos are cached for an hour.
+func FetchRepo(fullName string) (*github.RepoTrending, error) {
+	return fetchRepo(fullName)
+}
+
+// cachedFetcher returns the fetcher keeping the repos fetched by fetch for the ttl.
+// The errors aren't cached. Every call gets its own copy of the repo, so the overrides
+// merged by a run don't leak into the next ones.
+func cachedFetcher(fetch curation.Fetcher, ttl time.Duration) curation.Fetcher {
+	type cached struct {
+		repo      github.RepoTrending
+		fetchedAt time.Time
+	}
+
+	var (
+		mu    sync.Mutex
+		repos = make(map[string]cached)
+	)
+
+	return func(fullName string) (*github.RepoTrending, error) {
+		mu.Lock()
+		c, ok := repos[fullName]
+		mu.Unlock()
+
+		if ok && time.Since(c.fetchedAt) < ttl {
+			repo := c.repo
+			return &repo, nil
+		}
+
+		repo, err := fetch(fullName)
+		if err != nil {
+			return nil, err
+		}
+
+		mu.Lock()
+		repos[fullName] = cached{repo: *repo, fetchedAt: time.Now()}
+		mu.Unlock()
+
+		fetched := *repo
+		return &fetched, nil
+	}
+}
@@ -0,0 +1,108 @@
+// Package redisclient creates the Redis clients shared by the stores,
+// tuned for managed Redis servers that sometimes add a few seconds of latency.
+package redisclient
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// dialTimeout is the default timeout to establish a connection.
+	dialTimeout = 10 * time.Second
+	// ioTimeout is the default timeout of reads and writes.
+	ioTimeout = 5 * time.Second
+	// maxRetries is the default number of retries of a failed command.
+	maxRetries = 5
+	// minRetryBackoff and maxRetryBackoff bound the wait between two retries of a command.
+	minRetryBackoff = 100 * time.Millisecond
+	maxRetryBackoff = 2 * time.Second
+	// connectAttempts is the number of pings before giving up on a new client.
+	connectAttempts = 3
+)
+
+// Options parses the Redis URI and fills the settings it leaves unset with the defaults.
+// The pool and the timeouts can be configured through the query of the URI, e.g.
+// redis://localhost:6379/0?pool_size=20&dial_timeout=15s&read_timeout=10s&max_retries=10.
+func Options(redisURI string) (*redis.Options, error) {
+	opt, err := redis.ParseURL(redisURI)
+	if err != nil {
+		return nil, err
+	}
+
+	if opt.DialTimeout == 0 {
+		opt.DialTimeout = dialTimeout
+	}
+
+	if opt.ReadTimeout == 0 {
+		opt.ReadTimeout = ioTimeout
+	}
+
+	if opt.WriteTimeout == 0 {
+		opt.WriteTimeout = ioTimeout
+	}
+
+	if opt.MaxRetries == 0 {
+		opt.MaxRetries = maxRetries
+	}
+
+	if opt.MinRetryBackoff == 0 {
+		opt.MinRetryBackoff = minRetryBackoff
+	}
+
+	if opt.MaxRetryBackoff == 0 {
+		opt.MaxRetryBackoff = maxRetryBackoff
+	}
+
+	return opt, nil
+}
+
+// New returns a client of the Redis server at redisURI,
+// the connections are established on the first command.
+// The client has to be closed once it's not needed anymore.
+func New(redisURI string) (*redis.Client, error) {
+	opt, err := Options(redisURI)
+	if err != nil {
+		return nil, err
+	}
+
+	return redis.NewClient(opt), nil
+}
+
+// Connect returns a client connected to the Redis server at redisURI.
+// The server is pinged a few times before giving up, so an unreachable server
+// fails the run early instead of on the first command.
+func Connect(ctx context.Context, redisURI string) (*redis.Client, error) {
+	rdb, err := New(redisURI)
+	if err != nil {
+		return nil, err
+	}
+
+	opt := rdb.Options()
+
+	wait := opt.MinRetryBackoff
+	for attempt := 1; ; attempt++ {
+		err = rdb.Ping(ctx).Err()
+		if err == nil {
+			return rdb, nil
+		}
+
+		if attempt == connectAttempts || ctx.Err() != nil {
+			break
+		}
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+		}
+
+		wait *= 2
+	}
+
+	rdb.Close()
+
+	return nil, fmt.Errorf("connecting to redis at %s: %w", opt.Addr, err)
+}
@@ -0,0 +1,75 @@
+package redisclient
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptions(t *testing.T) {
+	var tests = []struct {
+		name        string
+		uri         string
+		poolSize    int
+		dialTimeout time.Duration
+		readTimeout time.Duration
+		maxRetries  int
+		hasErr      bool
+	}{
+		{
+			name:        "defaults",
+			uri:         "redis://default:@localhost:6379",
+			dialTimeout: dialTimeout,
+			readTimeout: ioTimeout,
+			maxRetries:  maxRetries,
+		},
+		{
+			name:        "configured in the uri",
+			uri:         "redis://localhost:6379/0?pool_size=20&dial_timeout=15s&read_timeout=10s&max_retries=10",
+			poolSize:    20,
+			dialTimeout: 15 * time.Second,
+			readTimeout: 10 * time.Second,
+			maxRetries:  10,
+		},
+		{
+			name:        "retries disabled",
+			uri:         "redis://localhost:6379/0?max_retries=-1",
+			dialTimeout: dialTimeout,
+			readTimeout: ioTimeout,
+			maxRetries:  -1,
+		},
+		{
+			name:   "invalid",
+			uri:    "localhost:6379",
+			hasErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			opt, err := Options(tc.uri)
+			if tc.hasErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.poolSize, opt.PoolSize)
+			require.Equal(t, tc.dialTimeout, opt.DialTimeout)
+			require.Equal(t, tc.readTimeout, opt.ReadTimeout)
+			require.Equal(t, tc.maxRetries, opt.MaxRetries)
+		})
+	}
+}
+
+func TestConnect(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// nothing listens on the port, connecting gives up once the context is done.
+	_, err := Connect(ctx, "redis://localhost:1/0?dial_timeout=100ms&max_retries=-1")
+	require.Error(t, err)
+}
@@ -0,0 +1,147 @@
+package schedule
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Duration is a time.Duration written as a string in JSON, e.g. "5m".
+type Duration time.Duration
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("invalid duration %s, expected a string like \"5m\"", b)
+	}
+
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(v)
+
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// Config describes the jobs of the scheduler,
+// the time zone, jitter and catch-up apply to the jobs that don't set their own.
+type Config struct {
+	// TimeZone is an IANA time zone, e.g. "America/Mexico_City". Defaults to UTC.
+	TimeZone string    `json:"timezone"`
+	Jitter   Duration  `json:"jitter"`
+	CatchUp  Duration  `json:"catch_up"`
+	Jobs     []JobSpec `json:"jobs"`
+}
+
+// JobSpec describes a job: the task it runs, its schedule and the options of the task.
+type JobSpec struct {
+	// Name identifies the job. Defaults to the task.
+	Name string `json:"name"`
+	// Task is the name of the task the job runs.
+	Task string `json:"task"`
+	// Schedule is a cron expression, see Cron.
+	Schedule string    `json:"schedule"`
+	TimeZone string    `json:"timezone"`
+	Jitter   *Duration `json:"jitter"`
+	CatchUp  *Duration `json:"catch_up"`
+
+	// Language is the language of the trending repos.
+	Language string `json:"language"`
+	// Channel is the channel the repos are published to.
+	Channel string `json:"channel"`
+	// Threaded publishes the repos as replies to a root note.
+	Threaded bool `json:"threaded"`
+	// Since is the trending period of a digest.
+	Since string `json:"since"`
+}
+
+// Task does the work of a job, given its spec.
+type Task func(ctx context.Context, spec JobSpec) error
+
+// LoadConfig reads the config from a JSON file.
+func LoadConfig(path string) (*Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var c Config
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	return &c, nil
+}
+
+// NewJobs returns the jobs of the config, running the given tasks.
+func (c *Config) NewJobs(tasks map[string]Task) ([]*Job, error) {
+	loc, err := time.LoadLocation(c.TimeZone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time zone %q: %w", c.TimeZone, err)
+	}
+
+	jobs := make([]*Job, 0, len(c.Jobs))
+	names := make(map[string]bool, len(c.Jobs))
+
+	for _, spec := range c.Jobs {
+		spec := spec
+
+		if spec.Name == "" {
+			spec.Name = spec.Task
+		}
+
+		if names[spec.Name] {
+			return nil, fmt.Errorf("job %s is defined twice, give the jobs different names", spec.Name)
+		}
+		names[spec.Name] = true
+
+		task, ok := tasks[spec.Task]
+		if !ok {
+			return nil, fmt.Errorf("job %s: unknown task %q", spec.Name, spec.Task)
+		}
+
+		schedule, err := Parse(spec.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("job %s: %w", spec.Name, err)
+		}
+
+		job := &Job{
+			Name:     spec.Name,
+			Schedule: schedule,
+			Location: loc,
+			Jitter:   time.Duration(c.Jitter),
+			CatchUp:  time.Duration(c.CatchUp),
+			Run: func(ctx context.Context) error {
+				return task(ctx, spec)
+			},
+		}
+
+		if spec.TimeZone != "" {
+			if job.Location, err = time.LoadLocation(spec.TimeZone); err != nil {
+				return nil, fmt.Errorf("job %s: invalid time zone %q: %w", spec.Name, spec.TimeZone, err)
+			}
+		}
+
+		if spec.Jitter != nil {
+			job.Jitter = time.Duration(*spec.Jitter)
+		}
+
+		if spec.CatchUp != nil {
+			job.CatchUp = time.Duration(*spec.CatchUp)
+		}
+
+		jobs = append(jobs, job)
+	}
+
+	return jobs, nil
+}
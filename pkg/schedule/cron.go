@@ -0,0 +1,240 @@
+// Package schedule runs the jobs of the bot on cron schedules,
+// a single instance at a time.
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cron is a parsed cron expression with the five standard fields:
+//
+//	minute hour day-of-month month day-of-week
+//
+// Fields take "*", values, ranges ("1-5"), steps ("*/15", "0-30/10") and lists ("1,15"),
+// months and days of the week also take their names ("jan", "mon").
+// The @yearly, @monthly, @weekly, @daily and @hourly shorthands are supported.
+type Cron struct {
+	expr                          string
+	minute, hour, dom, month, dow uint64
+	domStar, dowStar              bool
+}
+
+// field is the range of values of a cron field.
+type field struct {
+	name     string
+	min, max int
+	names    []string
+}
+
+var (
+	minuteField = field{name: "minute", min: 0, max: 59}
+	hourField   = field{name: "hour", min: 0, max: 23}
+	domField    = field{name: "day of month", min: 1, max: 31}
+	monthField  = field{name: "month", min: 1, max: 12, names: []string{
+		"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec",
+	}}
+	// 7 is Sunday too.
+	dowField = field{name: "day of week", min: 0, max: 7, names: []string{
+		"sun", "mon", "tue", "wed", "thu", "fri", "sat",
+	}}
+)
+
+var shorthands = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Parse parses a cron expression.
+func Parse(expr string) (*Cron, error) {
+	spec := strings.TrimSpace(expr)
+	if s, ok := shorthands[strings.ToLower(spec)]; ok {
+		spec = s
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid cron expression %q: expected 5 fields, got %d", expr, len(fields))
+	}
+
+	c := &Cron{expr: expr}
+
+	var err error
+	if c.minute, err = minuteField.parse(fields[0]); err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: %w", expr, err)
+	}
+
+	if c.hour, err = hourField.parse(fields[1]); err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: %w", expr, err)
+	}
+
+	if c.dom, err = domField.parse(fields[2]); err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: %w", expr, err)
+	}
+
+	if c.month, err = monthField.parse(fields[3]); err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: %w", expr, err)
+	}
+
+	if c.dow, err = dowField.parse(fields[4]); err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: %w", expr, err)
+	}
+
+	// Sunday is both 0 and 7.
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+
+	c.domStar = strings.HasPrefix(fields[2], "*")
+	c.dowStar = strings.HasPrefix(fields[4], "*")
+
+	return c, nil
+}
+
+// MustParse is like Parse but panics if the expression is invalid.
+func MustParse(expr string) *Cron {
+	c, err := Parse(expr)
+	if err != nil {
+		panic(err)
+	}
+
+	return c
+}
+
+// String returns the expression the schedule was parsed from.
+func (c *Cron) String() string {
+	return c.expr
+}
+
+// Next returns the first time matching the schedule strictly after t,
+// in the location of t. It returns the zero time if there's none within five years,
+// e.g. for "0 0 30 2 *".
+func (c *Cron) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if !has(c.month, int(t.Month())) {
+			t = advance(t, time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc))
+			continue
+		}
+
+		if !c.matchDay(t) {
+			t = advance(t, time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc))
+			continue
+		}
+
+		if !has(c.hour, t.Hour()) {
+			t = advance(t, time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc))
+			continue
+		}
+
+		if !has(c.minute, t.Minute()) {
+			t = t.Add(time.Minute)
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
+
+// advance returns next if it's after t, and the start of the next hour otherwise.
+// time.Date can go back in time for a wall clock time skipped by daylight saving time.
+func advance(t, next time.Time) time.Time {
+	if next.After(t) {
+		return next
+	}
+
+	return t.Add(time.Hour - time.Duration(t.Minute())*time.Minute)
+}
+
+// matchDay reports whether the day of t matches the schedule.
+// As in cron, when both the day of month and the day of week are restricted,
+// a day matching either of them matches.
+func (c *Cron) matchDay(t time.Time) bool {
+	dom := has(c.dom, t.Day())
+	dow := has(c.dow, int(t.Weekday()))
+
+	if c.domStar || c.dowStar {
+		return dom && dow
+	}
+
+	return dom || dow
+}
+
+func has(set uint64, v int) bool {
+	return set&(1<<uint(v)) != 0
+}
+
+// parse returns the set of values of the field, one bit per value.
+func (f field) parse(s string) (uint64, error) {
+	var set uint64
+
+	for _, part := range strings.Split(s, ",") {
+		step := 1
+		if r, st, ok := strings.Cut(part, "/"); ok {
+			n, err := strconv.Atoi(st)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step %q in the %s field", st, f.name)
+			}
+
+			part, step = r, n
+		}
+
+		lo, hi := f.min, f.max
+		if part != "*" {
+			from, to, isRange := strings.Cut(part, "-")
+
+			var err error
+			if lo, err = f.value(from); err != nil {
+				return 0, err
+			}
+
+			hi = lo
+			if isRange {
+				if hi, err = f.value(to); err != nil {
+					return 0, err
+				}
+			} else if step > 1 {
+				// "5/15" means from 5 to the end, every 15.
+				hi = f.max
+			}
+
+			if lo > hi {
+				return 0, fmt.Errorf("invalid range %q in the %s field", part, f.name)
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			set |= 1 << uint(v)
+		}
+	}
+
+	return set, nil
+}
+
+// value parses a single value of the field, a number or a name.
+func (f field) value(s string) (int, error) {
+	for i, name := range f.names {
+		if strings.EqualFold(s, name) {
+			return f.min + i, nil
+		}
+	}
+
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("invalid value %q in the %s field, expected %d-%d", s, f.name, f.min, f.max)
+	}
+
+	return v, nil
+}
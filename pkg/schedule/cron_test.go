@@ -0,0 +1,126 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	var tests = []struct {
+		name   string
+		expr   string
+		hasErr bool
+	}{
+		{name: "every minute", expr: "* * * * *"},
+		{name: "lists, ranges and steps", expr: "0,30 9-17/2 1-15 */3 1-5"},
+		{name: "names", expr: "0 16 * jan-jun mon,wed,FRI"},
+		{name: "sunday as 7", expr: "0 0 * * 7"},
+		{name: "shorthand", expr: "@daily"},
+		{name: "missing field", expr: "0 16 * *", hasErr: true},
+		{name: "out of range", expr: "60 * * * *", hasErr: true},
+		{name: "reversed range", expr: "0 17-9 * * *", hasErr: true},
+		{name: "zero step", expr: "*/0 * * * *", hasErr: true},
+		{name: "unknown name", expr: "0 0 * * someday", hasErr: true},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := Parse(tc.expr)
+			if tc.hasErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.expr, c.String())
+		})
+	}
+}
+
+func TestNext(t *testing.T) {
+	mexico, err := time.LoadLocation("America/Mexico_City")
+	require.NoError(t, err)
+
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	var tests = []struct {
+		name     string
+		expr     string
+		from     time.Time
+		expected time.Time
+	}{
+		{
+			name:     "later the same day",
+			expr:     "0 16 * * *",
+			from:     time.Date(2023, time.October, 6, 10, 0, 0, 0, time.UTC),
+			expected: time.Date(2023, time.October, 6, 16, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "strictly after",
+			expr:     "0 16 * * *",
+			from:     time.Date(2023, time.October, 6, 16, 0, 0, 0, time.UTC),
+			expected: time.Date(2023, time.October, 7, 16, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "every 15 minutes",
+			expr:     "*/15 * * * *",
+			from:     time.Date(2023, time.October, 6, 10, 14, 59, 0, time.UTC),
+			expected: time.Date(2023, time.October, 6, 10, 15, 0, 0, time.UTC),
+		},
+		{
+			name:     "next month",
+			expr:     "0 0 1 * *",
+			from:     time.Date(2023, time.December, 15, 0, 0, 0, 0, time.UTC),
+			expected: time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "weekdays",
+			expr:     "30 9 * * mon-fri",
+			from:     time.Date(2023, time.October, 6, 10, 0, 0, 0, time.UTC), // Friday
+			expected: time.Date(2023, time.October, 9, 9, 30, 0, 0, time.UTC),
+		},
+		{
+			name:     "day of month or day of week",
+			expr:     "0 0 13 * 5",
+			from:     time.Date(2023, time.October, 7, 0, 0, 0, 0, time.UTC),
+			expected: time.Date(2023, time.October, 13, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "leap day",
+			expr:     "0 0 29 2 *",
+			from:     time.Date(2023, time.March, 1, 0, 0, 0, 0, time.UTC),
+			expected: time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "time zone",
+			expr:     "0 11 * * *",
+			from:     time.Date(2023, time.October, 6, 0, 0, 0, 0, mexico),
+			expected: time.Date(2023, time.October, 6, 11, 0, 0, 0, mexico),
+		},
+		{
+			name:     "skipped hour of daylight saving time",
+			expr:     "30 2 * * *",
+			from:     time.Date(2023, time.March, 12, 0, 0, 0, 0, newYork),
+			expected: time.Date(2023, time.March, 13, 2, 30, 0, 0, newYork),
+		},
+		{
+			name: "never",
+			expr: "0 0 30 2 *",
+			from: time.Date(2023, time.October, 6, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			next := MustParse(tc.expr).Next(tc.from)
+			require.True(t, tc.expected.Equal(next), "expected %s, got %s", tc.expected, next)
+		})
+	}
+}
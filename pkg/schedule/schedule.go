@@ -0,0 +1,240 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/Arturomtz8/github-inspector/pkg/lock"
+)
+
+const (
+	// lockKey is the Redis key of the lock held by the running instance.
+	lockKey = "schedule-lock"
+	// lastRunKey is the Redis hash of the time every job last ran for, by job name.
+	lastRunKey = "schedule-last-run"
+	// defaultLockLease is how long the lock outlives an instance that died.
+	defaultLockLease = time.Minute
+)
+
+// Job is a task run on a cron schedule.
+type Job struct {
+	// Name identifies the job, its last run is stored under it.
+	Name string
+	// Schedule tells when the job runs.
+	Schedule *Cron
+	// Location is the time zone of the schedule. Defaults to UTC.
+	Location *time.Location
+	// Jitter delays every run by a random duration up to it,
+	// so the runs don't always happen at the same second.
+	Jitter time.Duration
+	// CatchUp is how late a run missed while no instance was running
+	// can still be made up when the scheduler starts. 0 never makes up missed runs.
+	CatchUp time.Duration
+	// Run does the work of the job.
+	Run func(ctx context.Context) error
+}
+
+func (j *Job) location() *time.Location {
+	if j.Location == nil {
+		return time.UTC
+	}
+
+	return j.Location
+}
+
+// next returns the first run of the job strictly after t.
+func (j *Job) next(t time.Time) time.Time {
+	return j.Schedule.Next(t.In(j.location()))
+}
+
+// plan returns the time the job has to run for, given the time it last ran for.
+// A run missed less than CatchUp ago is due right away, at now, and missed is true.
+func (j *Job) plan(last, now time.Time) (at time.Time, missed bool) {
+	if !last.IsZero() && j.CatchUp > 0 {
+		slot := j.next(last)
+		if !slot.IsZero() && !slot.After(now) && now.Sub(slot) <= j.CatchUp {
+			return now, true
+		}
+	}
+
+	return j.next(now), false
+}
+
+// jitter returns a random delay up to the jitter of the job.
+func (j *Job) jitter() time.Duration {
+	if j.Jitter <= 0 {
+		return 0
+	}
+
+	return time.Duration(rand.Int63n(int64(j.Jitter)))
+}
+
+// Scheduler runs the jobs on their schedules. Several instances can run,
+// only the one holding the Redis lock runs the jobs, the others stand by.
+type Scheduler struct {
+	rdb  *redis.Client
+	jobs []*Job
+	// LockLease is how long the lock outlives an instance that died,
+	// it's the longest a standby instance waits to take over. Defaults to a minute.
+	LockLease time.Duration
+}
+
+// New returns a scheduler running the jobs, keeping its state in Redis.
+func New(rdb *redis.Client, jobs ...*Job) *Scheduler {
+	return &Scheduler{rdb: rdb, jobs: jobs}
+}
+
+func (s *Scheduler) lease() time.Duration {
+	if s.LockLease <= 0 {
+		return defaultLockLease
+	}
+
+	return s.LockLease
+}
+
+// Run runs the jobs until the context is done. It returns the error of the context.
+func (s *Scheduler) Run(ctx context.Context) error {
+	l := lock.New(s.rdb, lockKey, s.lease())
+	standby := false
+
+	for {
+		ok, err := l.Acquire(ctx)
+		switch {
+		case err != nil:
+			log.Printf("error occurred acquiring the scheduler lock: %v", err)
+		case !ok:
+			if !standby {
+				log.Printf("another instance runs the jobs, standing by")
+			}
+			standby = true
+		default:
+			log.Printf("running the jobs")
+			standby = false
+			s.lead(ctx, l)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(s.lease() / 2):
+		}
+	}
+}
+
+// lead runs the jobs while the lock is held, releasing it on return.
+func (s *Scheduler) lead(ctx context.Context, l *lock.Lock) {
+	ctx, cancel := l.Keep(ctx)
+	defer cancel()
+
+	defer func() {
+		// The context of the run is done, the lock is released on its own.
+		if err := l.Release(context.Background()); err != nil {
+			log.Printf("error occurred releasing the scheduler lock: %v", err)
+		}
+	}()
+
+	now := time.Now()
+
+	// slots holds the time every job has to run for,
+	// fires the time it actually runs, with its jitter.
+	slots := make(map[*Job]time.Time, len(s.jobs))
+	fires := make(map[*Job]time.Time, len(s.jobs))
+
+	for _, job := range s.jobs {
+		last, err := s.lastRun(ctx, job)
+		if err != nil {
+			log.Printf("error occurred reading the last run of %s: %v", job.Name, err)
+		}
+
+		slot, missed := job.plan(last, now)
+		if slot.IsZero() {
+			log.Printf("%s never runs, its schedule %q has no next run", job.Name, job.Schedule)
+			continue
+		}
+
+		if missed {
+			log.Printf("%s missed its run after %s, making it up", job.Name, last.Format(time.RFC3339))
+		} else {
+			log.Printf("%s runs next at %s", job.Name, slot.Format(time.RFC3339))
+		}
+
+		slots[job], fires[job] = slot, slot.Add(job.jitter())
+	}
+
+	for len(fires) != 0 {
+		var job *Job
+		for j, fire := range fires {
+			if job == nil || fire.Before(fires[job]) {
+				job = j
+			}
+		}
+
+		timer := time.NewTimer(time.Until(fires[job]))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		log.Printf("running %s", job.Name)
+
+		if err := s.run(ctx, job); err != nil {
+			log.Printf("error occurred running %s: %v", job.Name, err)
+		}
+
+		if ctx.Err() != nil {
+			// The lock was lost, the next instance runs the job again if needed.
+			return
+		}
+
+		if err := s.setLastRun(ctx, job, slots[job]); err != nil {
+			log.Printf("error occurred storing the last run of %s: %v", job.Name, err)
+		}
+
+		// The runs missed while the job was running are skipped.
+		slot := job.next(time.Now())
+		if slot.IsZero() {
+			delete(slots, job)
+			delete(fires, job)
+			continue
+		}
+
+		slots[job], fires[job] = slot, slot.Add(job.jitter())
+	}
+
+	<-ctx.Done()
+}
+
+// run runs the job, turning its panics into errors so the scheduler keeps going.
+func (s *Scheduler) run(ctx context.Context, job *Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return job.Run(ctx)
+}
+
+// lastRun returns the time the job last ran for, the zero time if it never ran.
+func (s *Scheduler) lastRun(ctx context.Context, job *Job) (time.Time, error) {
+	value, err := s.rdb.HGet(ctx, lastRunKey, job.Name).Result()
+	if err == redis.Nil {
+		return time.Time{}, nil
+	} else if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Parse(time.RFC3339, value)
+}
+
+func (s *Scheduler) setLastRun(ctx context.Context, job *Job, t time.Time) error {
+	return s.rdb.HSet(ctx, lastRunKey, job.Name, t.UTC().Format(time.RFC3339)).Err()
+}
@@ -0,0 +1,178 @@
+package schedule
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/Arturomtz8/github-inspector/pkg/redisclient"
+)
+
+func TestPlan(t *testing.T) {
+	now := time.Date(2023, time.October, 6, 18, 0, 0, 0, time.UTC)
+
+	var tests = []struct {
+		name     string
+		catchUp  time.Duration
+		last     time.Time
+		expected time.Time
+		missed   bool
+	}{
+		{
+			name:     "never ran",
+			catchUp:  6 * time.Hour,
+			expected: time.Date(2023, time.October, 7, 16, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "ran for the last slot",
+			catchUp:  6 * time.Hour,
+			last:     time.Date(2023, time.October, 6, 16, 0, 0, 0, time.UTC),
+			expected: time.Date(2023, time.October, 7, 16, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "missed the last slot",
+			catchUp:  6 * time.Hour,
+			last:     time.Date(2023, time.October, 5, 16, 0, 0, 0, time.UTC),
+			expected: now,
+			missed:   true,
+		},
+		{
+			name:     "missed the last slot too long ago",
+			catchUp:  time.Hour,
+			last:     time.Date(2023, time.October, 5, 16, 0, 0, 0, time.UTC),
+			expected: time.Date(2023, time.October, 7, 16, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "catch-up disabled",
+			last:     time.Date(2023, time.October, 5, 16, 0, 0, 0, time.UTC),
+			expected: time.Date(2023, time.October, 7, 16, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			job := &Job{Schedule: MustParse("0 16 * * *"), CatchUp: tc.catchUp}
+
+			at, missed := job.plan(tc.last, now)
+			require.True(t, tc.expected.Equal(at), "expected %s, got %s", tc.expected, at)
+			require.Equal(t, tc.missed, missed)
+		})
+	}
+}
+
+func TestJitter(t *testing.T) {
+	job := &Job{}
+	require.Zero(t, job.jitter())
+
+	job.Jitter = time.Minute
+	for i := 0; i < 100; i++ {
+		d := job.jitter()
+		require.GreaterOrEqual(t, d, time.Duration(0))
+		require.Less(t, d, time.Minute)
+	}
+}
+
+func TestNewJobs(t *testing.T) {
+	var c Config
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"timezone": "America/Mexico_City",
+		"jitter": "5m",
+		"jobs": [
+			{"task": "publish", "schedule": "0 11 * * *", "language": "Go"},
+			{"name": "digest-rust", "task": "digest", "schedule": "@daily", "timezone": "UTC", "jitter": "0s", "catch_up": "6h"}
+		]
+	}`), &c))
+
+	var ran []JobSpec
+	task := func(_ context.Context, spec JobSpec) error {
+		ran = append(ran, spec)
+		return nil
+	}
+
+	jobs, err := c.NewJobs(map[string]Task{"publish": task, "digest": task})
+	require.NoError(t, err)
+	require.Len(t, jobs, 2)
+
+	require.Equal(t, "publish", jobs[0].Name)
+	require.Equal(t, "America/Mexico_City", jobs[0].Location.String())
+	require.Equal(t, 5*time.Minute, jobs[0].Jitter)
+	require.Zero(t, jobs[0].CatchUp)
+
+	require.Equal(t, "digest-rust", jobs[1].Name)
+	require.Equal(t, time.UTC, jobs[1].Location)
+	require.Zero(t, jobs[1].Jitter)
+	require.Equal(t, 6*time.Hour, jobs[1].CatchUp)
+
+	require.NoError(t, jobs[0].Run(context.Background()))
+	require.Equal(t, "Go", ran[0].Language)
+
+	_, err = c.NewJobs(map[string]Task{"publish": task})
+	require.Error(t, err, "the digest task is unknown")
+
+	c.Jobs[1].Schedule = "0 25 * * *"
+	_, err = c.NewJobs(map[string]Task{"publish": task, "digest": task})
+	require.Error(t, err)
+
+	c.Jobs[1].Name = "publish"
+	_, err = c.NewJobs(map[string]Task{"publish": task, "digest": task})
+	require.Error(t, err, "the job names are repeated")
+
+	require.Error(t, json.Unmarshal([]byte(`{"jitter": 300}`), &c))
+}
+
+func TestSchedulerCatchUp(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping short mode")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	rdb, err := redisclient.Connect(ctx, os.Getenv("REDIS_URI"))
+	require.NoError(t, err)
+	defer rdb.Close()
+
+	require.NoError(t, rdb.Del(ctx, lockKey, lastRunKey).Err())
+	defer rdb.Del(context.Background(), lockKey, lastRunKey)
+
+	ran := make(chan struct{})
+	job := &Job{
+		Name:     "test",
+		Schedule: MustParse("0 16 * * *"),
+		CatchUp:  48 * time.Hour,
+		Run: func(context.Context) error {
+			close(ran)
+			return nil
+		},
+	}
+
+	// the job missed the run after this one.
+	last := job.next(time.Now().Add(-48 * time.Hour))
+	require.NoError(t, rdb.HSet(ctx, lastRunKey, job.Name, last.Format(time.RFC3339)).Err())
+
+	s := New(rdb, job)
+	s.LockLease = time.Second
+
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+
+	select {
+	case <-ran:
+	case <-ctx.Done():
+		t.Fatal("the missed run wasn't made up")
+	}
+
+	// the other instances stand by while the jobs run.
+	ok, err := rdb.Exists(ctx, lockKey).Result()
+	require.NoError(t, err)
+	require.Equal(t, int64(1), ok)
+
+	cancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+}
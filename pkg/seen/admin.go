@@ -0,0 +1,176 @@
+package seen
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// Entry is a published repo as kept in a namespace of the store.
+type Entry struct {
+	FullName string
+	Value    string
+	// Record is nil for repos seen before records were introduced.
+	Record *Record
+	// TTL is the time left before the entry expires, 0 if it never expires.
+	TTL time.Duration
+	// Reserved reports whether a run is publishing the repo right now.
+	Reserved bool
+}
+
+// exportedEntry is a line of the export of a namespace.
+type exportedEntry struct {
+	Repo  string `json:"repo"`
+	Value string `json:"value"`
+	// TTLSeconds is the time left before the entry expires, 0 if it never expires.
+	TTLSeconds int64 `json:"ttl_seconds"`
+}
+
+// List returns the repos published in the namespace, sorted by full name.
+func List(ctx context.Context, s Store, n Namespace) ([]Entry, error) {
+	keys, err := s.Keys(ctx, n.Prefix())
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(keys)
+
+	var entries []Entry
+
+	for _, key := range keys {
+		if isLockKey(key) {
+			continue
+		}
+
+		fullName, ok := n.FullName(key)
+		if !ok {
+			continue
+		}
+
+		entry, ok, err := Get(ctx, s, n, fullName)
+		if err != nil {
+			return nil, err
+		}
+
+		// The key expired since it was listed.
+		if !ok {
+			continue
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// Get returns the entry of the repo in the namespace,
+// reporting false if the repo was never published.
+func Get(ctx context.Context, s Store, n Namespace, fullName string) (Entry, bool, error) {
+	key := n.Key(fullName)
+
+	value, ok, err := s.Get(ctx, key)
+	if err != nil || !ok {
+		return Entry{}, false, err
+	}
+
+	ttl, ok, err := s.TTL(ctx, key)
+	if err != nil || !ok {
+		return Entry{}, false, err
+	}
+
+	_, reserved, err := s.Get(ctx, LockKey(key))
+	if err != nil {
+		return Entry{}, false, err
+	}
+
+	entry := Entry{FullName: fullName, Value: value, TTL: ttl, Reserved: reserved}
+
+	if record, ok := ParseRecord(value); ok {
+		entry.Record = &record
+	}
+
+	return entry, true, nil
+}
+
+// Forget removes the repo and its reservation from the namespace,
+// so the next run publishes it again.
+func Forget(ctx context.Context, s Store, n Namespace, fullName string) error {
+	key := n.Key(fullName)
+
+	if err := s.Delete(ctx, key); err != nil {
+		return err
+	}
+
+	return s.Delete(ctx, LockKey(key))
+}
+
+// Mark stores the record of the repo in the namespace for the given ttl, 0 means forever.
+// Blocked records are kept forever whatever the ttl.
+func Mark(ctx context.Context, s Store, n Namespace, fullName string, record Record, ttl time.Duration) error {
+	if record.Blocked {
+		ttl = 0
+	}
+
+	return s.Commit(ctx, n.Key(fullName), record.String(), ttl)
+}
+
+// Export writes the repos published in the namespace to w as JSON lines.
+// It returns the number of repos written.
+func Export(ctx context.Context, s Store, n Namespace, w io.Writer) (int, error) {
+	entries, err := List(ctx, s, n)
+	if err != nil {
+		return 0, err
+	}
+
+	enc := json.NewEncoder(w)
+
+	for i, entry := range entries {
+		line := exportedEntry{
+			Repo:  entry.FullName,
+			Value: entry.Value,
+			// Rounded up, so an entry about to expire isn't imported as one that never does.
+			TTLSeconds: int64((entry.TTL + time.Second - 1) / time.Second),
+		}
+
+		if err := enc.Encode(line); err != nil {
+			return i, err
+		}
+	}
+
+	return len(entries), nil
+}
+
+// Import reads the JSON lines written by Export from r and stores them in the namespace,
+// overwriting the repos already there. It returns the number of repos imported.
+func Import(ctx context.Context, s Store, n Namespace, r io.Reader) (int, error) {
+	imported := 0
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var entry exportedEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return imported, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		if entry.Repo == "" {
+			return imported, fmt.Errorf("line %d: missing repo", line)
+		}
+
+		ttl := time.Duration(entry.TTLSeconds) * time.Second
+		if err := s.Commit(ctx, n.Key(entry.Repo), entry.Value, ttl); err != nil {
+			return imported, err
+		}
+
+		imported++
+	}
+
+	return imported, scanner.Err()
+}
@@ -0,0 +1,304 @@
+package seen
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// fileBucket is the bucket of the database holding the keys.
+var fileBucket = []byte("seen")
+
+// fileLockTimeout is how long an operation waits for another process to close the database.
+const fileLockTimeout = 30 * time.Second
+
+// FileStore keeps the keys in an embedded bbolt database on disk,
+// so they survive restarts without running a Redis server.
+// The database is opened for every operation and bbolt locks the file while it's open,
+// so several processes can share it: every operation runs in a transaction
+// and two runs never both reserve the same key.
+type FileStore struct {
+	path string
+}
+
+// NewFileStore returns a store backed by the database at path,
+// the database is created on the first change.
+// A JSON file written by a previous version of the store is converted to a database,
+// the original file is kept next to it with the .bak extension.
+func NewFileStore(path string) (*FileStore, error) {
+	s := &FileStore{path: path}
+
+	if err := s.convertJSON(); err != nil {
+		return nil, err
+	}
+
+	// Fail early if the file exists but isn't a database.
+	if err := s.view(func(entries, time.Time) {}); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// convertJSON moves the keys of a JSON file at the path of the store to a database.
+func (s *FileStore) convertJSON() error {
+	b, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	trimmed := bytes.TrimSpace(b)
+	if len(trimmed) != 0 && trimmed[0] != '{' {
+		// It's already a database.
+		return nil
+	}
+
+	m := make(entries)
+	if len(trimmed) != 0 {
+		if err := json.Unmarshal(trimmed, &m); err != nil {
+			return fmt.Errorf("converting %s: %w", s.path, err)
+		}
+	}
+
+	if err := os.Rename(s.path, s.path+".bak"); err != nil {
+		return err
+	}
+
+	return s.update(func(stored entries, _ time.Time) {
+		for key, e := range m {
+			stored[key] = e
+		}
+	})
+}
+
+// open opens the database, waiting for the other processes using it to close it.
+func (s *FileStore) open(readOnly bool) (*bolt.DB, error) {
+	return bolt.Open(s.path, 0o600, &bolt.Options{Timeout: fileLockTimeout, ReadOnly: readOnly})
+}
+
+// load reads the entries of the bucket.
+func load(b *bolt.Bucket) (entries, error) {
+	m := make(entries)
+	if b == nil {
+		return m, nil
+	}
+
+	err := b.ForEach(func(k, v []byte) error {
+		var e entry
+		if err := json.Unmarshal(v, &e); err != nil {
+			return fmt.Errorf("invalid key %s: %w", k, err)
+		}
+
+		m[string(k)] = e
+		return nil
+	})
+
+	return m, err
+}
+
+// view applies fn to the entries in a read-only transaction.
+func (s *FileStore) view(fn func(m entries, now time.Time)) error {
+	if _, err := os.Stat(s.path); errors.Is(err, fs.ErrNotExist) {
+		fn(make(entries), time.Now())
+		return nil
+	}
+
+	db, err := s.open(true)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return db.View(func(tx *bolt.Tx) error {
+		m, err := load(tx.Bucket(fileBucket))
+		if err != nil {
+			return err
+		}
+
+		fn(m, time.Now())
+		return nil
+	})
+}
+
+// update applies fn to the entries in a read-write transaction,
+// storing the entries it changed and dropping the expired ones.
+func (s *FileStore) update(fn func(m entries, now time.Time)) error {
+	db, err := s.open(false)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(fileBucket)
+		if err != nil {
+			return err
+		}
+
+		m, err := load(b)
+		if err != nil {
+			return err
+		}
+
+		before := make(entries, len(m))
+		for key, e := range m {
+			before[key] = e
+		}
+
+		now := time.Now()
+		fn(m, now)
+		m.prune(now)
+
+		for key := range before {
+			if _, ok := m[key]; !ok {
+				if err := b.Delete([]byte(key)); err != nil {
+					return err
+				}
+			}
+		}
+
+		for key, e := range m {
+			if old, ok := before[key]; ok && old == e {
+				continue
+			}
+
+			v, err := json.Marshal(e)
+			if err != nil {
+				return err
+			}
+
+			if err := b.Put([]byte(key), v); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Get implements Store.
+func (s *FileStore) Get(_ context.Context, key string) (string, bool, error) {
+	var (
+		value string
+		ok    bool
+	)
+
+	err := s.view(func(m entries, now time.Time) {
+		var e entry
+		e, ok = m.get(key, now)
+		value = e.Value
+	})
+
+	return value, ok, err
+}
+
+// GetMany implements Store.
+func (s *FileStore) GetMany(_ context.Context, keys []string) (map[string]string, error) {
+	var values map[string]string
+
+	err := s.view(func(m entries, now time.Time) {
+		values = m.getMany(keys, now)
+	})
+
+	return values, err
+}
+
+// Reserve implements Store.
+func (s *FileStore) Reserve(_ context.Context, key, token string, lease time.Duration) (bool, error) {
+	var reserved bool
+
+	err := s.update(func(m entries, now time.Time) {
+		reserved = m.reserve(key, token, lease, now)
+	})
+
+	return reserved, err
+}
+
+// ReserveMany implements Store, the keys are reserved in a single transaction.
+func (s *FileStore) ReserveMany(_ context.Context, keys []string, token string, lease time.Duration) ([]bool, error) {
+	var reserved []bool
+
+	err := s.update(func(m entries, now time.Time) {
+		reserved = m.reserveMany(keys, token, lease, now)
+	})
+
+	return reserved, err
+}
+
+// Commit implements Store.
+func (s *FileStore) Commit(_ context.Context, key, value string, ttl time.Duration) error {
+	return s.update(func(m entries, now time.Time) {
+		m.commit(key, value, ttl, now)
+	})
+}
+
+// Release implements Store.
+func (s *FileStore) Release(_ context.Context, key, token string) error {
+	return s.update(func(m entries, now time.Time) {
+		m.release(key, token, now)
+	})
+}
+
+// Extend implements Store.
+func (s *FileStore) Extend(_ context.Context, key, token string, lease time.Duration) error {
+	return s.update(func(m entries, now time.Time) {
+		m.extend(key, token, lease, now)
+	})
+}
+
+// TTL implements Store.
+func (s *FileStore) TTL(_ context.Context, key string) (time.Duration, bool, error) {
+	var (
+		ttl time.Duration
+		ok  bool
+	)
+
+	err := s.view(func(m entries, now time.Time) {
+		ttl, ok = m.ttl(key, now)
+	})
+
+	return ttl, ok, err
+}
+
+// Delete implements Store.
+func (s *FileStore) Delete(_ context.Context, key string) error {
+	return s.update(func(m entries, _ time.Time) {
+		delete(m, key)
+	})
+}
+
+// Keys implements Store.
+func (s *FileStore) Keys(_ context.Context, prefix string) ([]string, error) {
+	var keys []string
+
+	err := s.view(func(m entries, now time.Time) {
+		keys = m.keys(prefix, now)
+	})
+
+	return keys, err
+}
+
+// Rename implements Store.
+func (s *FileStore) Rename(_ context.Context, from, to string) (bool, error) {
+	var renamed bool
+
+	err := s.update(func(m entries, now time.Time) {
+		renamed = m.rename(from, to, now)
+	})
+
+	return renamed, err
+}
+
+// Close implements Store.
+func (s *FileStore) Close() error {
+	return nil
+}
@@ -0,0 +1,254 @@
+package seen
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// entry is a key kept by the in-memory and file stores.
+type entry struct {
+	Value     string    `json:"value"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (e entry) expired(now time.Time) bool {
+	return !e.ExpiresAt.IsZero() && !now.Before(e.ExpiresAt)
+}
+
+// entries implements the two phases on a plain map,
+// it's shared by the in-memory and file stores.
+type entries map[string]entry
+
+// get returns the entry of the key if it's not expired.
+func (m entries) get(key string, now time.Time) (entry, bool) {
+	e, ok := m[key]
+	if !ok || e.expired(now) {
+		return entry{}, false
+	}
+
+	return e, true
+}
+
+func (m entries) reserve(key, token string, lease time.Duration, now time.Time) bool {
+	if _, ok := m.get(key, now); ok {
+		return false
+	}
+
+	m[key] = entry{Value: reservation(token), ExpiresAt: now.Add(lease)}
+
+	return true
+}
+
+func (m entries) getMany(keys []string, now time.Time) map[string]string {
+	values := make(map[string]string, len(keys))
+	for _, key := range keys {
+		if e, ok := m.get(key, now); ok {
+			values[key] = e.Value
+		}
+	}
+
+	return values
+}
+
+func (m entries) reserveMany(keys []string, token string, lease time.Duration, now time.Time) []bool {
+	reserved := make([]bool, len(keys))
+	for i, key := range keys {
+		reserved[i] = m.reserve(key, token, lease, now)
+	}
+
+	return reserved
+}
+
+func (m entries) commit(key, value string, ttl time.Duration, now time.Time) {
+	e := entry{Value: value}
+	if ttl > 0 {
+		e.ExpiresAt = now.Add(ttl)
+	}
+
+	m[key] = e
+}
+
+func (m entries) release(key, token string, now time.Time) {
+	if e, ok := m.get(key, now); ok && e.Value == reservation(token) {
+		delete(m, key)
+	}
+}
+
+func (m entries) extend(key, token string, lease time.Duration, now time.Time) {
+	if e, ok := m.get(key, now); ok && e.Value == reservation(token) {
+		e.ExpiresAt = now.Add(lease)
+		m[key] = e
+	}
+}
+
+// ttl returns the time left before the key expires, 0 if it never expires.
+func (m entries) ttl(key string, now time.Time) (time.Duration, bool) {
+	e, ok := m.get(key, now)
+	if !ok {
+		return 0, false
+	}
+
+	if e.ExpiresAt.IsZero() {
+		return 0, true
+	}
+
+	return e.ExpiresAt.Sub(now), true
+}
+
+func (m entries) keys(prefix string, now time.Time) []string {
+	var keys []string
+	for key, e := range m {
+		if !e.expired(now) && strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+func (m entries) rename(from, to string, now time.Time) bool {
+	e, ok := m.get(from, now)
+	if !ok {
+		return false
+	}
+
+	if _, exists := m.get(to, now); exists {
+		return false
+	}
+
+	m[to] = e
+	delete(m, from)
+
+	return true
+}
+
+// prune drops the expired entries.
+func (m entries) prune(now time.Time) {
+	for key, e := range m {
+		if e.expired(now) {
+			delete(m, key)
+		}
+	}
+}
+
+// MemoryStore keeps the keys in memory, they are lost when the process ends.
+// It's meant for local runs and tests.
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries entries
+}
+
+// NewMemoryStore returns an empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(entries)}
+}
+
+// Get implements Store.
+func (s *MemoryStore) Get(_ context.Context, key string) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries.get(key, time.Now())
+
+	return e.Value, ok, nil
+}
+
+// GetMany implements Store.
+func (s *MemoryStore) GetMany(_ context.Context, keys []string) (map[string]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.entries.getMany(keys, time.Now()), nil
+}
+
+// Reserve implements Store.
+func (s *MemoryStore) Reserve(_ context.Context, key, token string, lease time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.entries.reserve(key, token, lease, time.Now()), nil
+}
+
+// ReserveMany implements Store.
+func (s *MemoryStore) ReserveMany(_ context.Context, keys []string, token string, lease time.Duration) ([]bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.entries.reserveMany(keys, token, lease, time.Now()), nil
+}
+
+// Commit implements Store.
+func (s *MemoryStore) Commit(_ context.Context, key, value string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries.commit(key, value, ttl, time.Now())
+
+	return nil
+}
+
+// Release implements Store.
+func (s *MemoryStore) Release(_ context.Context, key, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries.release(key, token, time.Now())
+
+	return nil
+}
+
+// Extend implements Store.
+func (s *MemoryStore) Extend(_ context.Context, key, token string, lease time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries.extend(key, token, lease, time.Now())
+
+	return nil
+}
+
+// TTL implements Store.
+func (s *MemoryStore) TTL(_ context.Context, key string) (time.Duration, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ttl, ok := s.entries.ttl(key, time.Now())
+
+	return ttl, ok, nil
+}
+
+// Delete implements Store.
+func (s *MemoryStore) Delete(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+
+	return nil
+}
+
+// Keys implements Store.
+func (s *MemoryStore) Keys(_ context.Context, prefix string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.entries.keys(prefix, time.Now()), nil
+}
+
+// Rename implements Store.
+func (s *MemoryStore) Rename(_ context.Context, from, to string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.entries.rename(from, to, time.Now()), nil
+}
+
+// Close implements Store.
+func (s *MemoryStore) Close() error {
+	return nil
+}
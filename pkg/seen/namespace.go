@@ -0,0 +1,89 @@
+package seen
+
+import (
+	"context"
+	"strings"
+)
+
+const (
+	// keyPrefix prefixes every namespaced key.
+	keyPrefix = "seen:"
+	// lockSuffix is appended to the key of a repo to get its reservation key.
+	lockSuffix = ":lock"
+)
+
+// Namespace keeps apart the keys of every sink, channel and language,
+// so several bots can share a store.
+type Namespace struct {
+	// Sink is the destination the repos are published to, e.g. "nostr".
+	Sink string
+	// Channel is the account or channel of the sink the repos are published to.
+	Channel string
+	// Language is the programming language of the repos.
+	Language string
+}
+
+// Prefix returns the prefix of every key in the namespace.
+func (n Namespace) Prefix() string {
+	return keyPrefix + n.Sink + ":" + n.Channel + ":" + strings.ToLower(n.Language) + ":"
+}
+
+// Key returns the key of the repo in the namespace.
+func (n Namespace) Key(fullName string) string {
+	return n.Prefix() + fullName
+}
+
+// LockKey returns the key holding the reservation of the repo of key.
+func LockKey(key string) string {
+	return key + lockSuffix
+}
+
+// isLockKey reports whether the key holds a reservation.
+func isLockKey(key string) bool {
+	return strings.HasSuffix(key, lockSuffix)
+}
+
+// FullName returns the repo of a key in the namespace,
+// reporting false if the key doesn't belong to it.
+func (n Namespace) FullName(key string) (string, bool) {
+	if !strings.HasPrefix(key, n.Prefix()) {
+		return "", false
+	}
+
+	return strings.TrimPrefix(key, n.Prefix()), true
+}
+
+// isLegacyKey reports whether the key was stored before namespaces were introduced,
+// those keys are the bare full name of the repo, like "owner/name".
+func isLegacyKey(key string) bool {
+	return strings.Count(key, "/") == 1 && !strings.Contains(key, ":")
+}
+
+// Migrate moves the keys stored before namespaces were introduced into the namespace,
+// keeping their values and TTLs. Keys already present in the namespace are left untouched.
+// It returns the number of keys moved.
+func Migrate(ctx context.Context, s Store, n Namespace) (int, error) {
+	keys, err := s.Keys(ctx, "")
+	if err != nil {
+		return 0, err
+	}
+
+	moved := 0
+
+	for _, key := range keys {
+		if !isLegacyKey(key) {
+			continue
+		}
+
+		ok, err := s.Rename(ctx, key, n.Key(key))
+		if err != nil {
+			return moved, err
+		}
+
+		if ok {
+			moved++
+		}
+	}
+
+	return moved, nil
+}
@@ -0,0 +1,34 @@
+package seen
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Record is the value kept for a published repo.
+type Record struct {
+	URL         string    `json:"url"`
+	Stars       int       `json:"stars"`
+	PublishedAt time.Time `json:"published_at"`
+	// EventID is the ID of the event or post the repo was published with.
+	EventID string `json:"event_id,omitempty"`
+	// Blocked repos are never published again.
+	Blocked bool `json:"blocked,omitempty"`
+}
+
+// ParseRecord parses the value of a seen repo.
+// Repos seen before records were introduced only hold their URL,
+// they are reported as not parsed.
+func ParseRecord(value string) (Record, bool) {
+	var record Record
+	if err := json.Unmarshal([]byte(value), &record); err != nil {
+		return Record{}, false
+	}
+
+	return record, true
+}
+
+func (r Record) String() string {
+	b, _ := json.Marshal(r)
+	return string(b)
+}
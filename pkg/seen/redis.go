@@ -0,0 +1,193 @@
+package seen
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/Arturomtz8/github-inspector/pkg/redisclient"
+)
+
+// releaseScript deletes a reservation only if it's still held by the given token,
+// so a run never releases a key reserved by another run or already committed.
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// extendScript extends a reservation only if it's still held by the given token.
+var extendScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// RedisStore keeps the keys in Redis, with their TTLs as expiration.
+type RedisStore struct {
+	rdb *redis.Client
+	// shared tells whether the client belongs to the caller,
+	// closing the store leaves it open then.
+	shared bool
+}
+
+// NewRedisStore returns a store connected to the given Redis URI.
+func NewRedisStore(redisURI string) (*RedisStore, error) {
+	rdb, err := redisclient.New(redisURI)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RedisStore{rdb: rdb}, nil
+}
+
+// NewRedisStoreWithClient returns a store using the given client,
+// so it shares its connection pool with the rest of the run.
+// Closing the store doesn't close the client.
+func NewRedisStoreWithClient(rdb *redis.Client) *RedisStore {
+	return &RedisStore{rdb: rdb, shared: true}
+}
+
+// Get implements Store.
+func (s *RedisStore) Get(ctx context.Context, key string) (string, bool, error) {
+	value, err := s.rdb.Get(ctx, key).Result()
+	if err == redis.Nil {
+		return "", false, nil
+	} else if err != nil {
+		return "", false, err
+	}
+
+	return value, true, nil
+}
+
+// GetMany implements Store, the keys are read with a single MGET.
+func (s *RedisStore) GetMany(ctx context.Context, keys []string) (map[string]string, error) {
+	values := make(map[string]string, len(keys))
+	if len(keys) == 0 {
+		return values, nil
+	}
+
+	res, err := s.rdb.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, v := range res {
+		// Missing keys are nil.
+		if value, ok := v.(string); ok {
+			values[keys[i]] = value
+		}
+	}
+
+	return values, nil
+}
+
+// Reserve implements Store.
+func (s *RedisStore) Reserve(ctx context.Context, key, token string, lease time.Duration) (bool, error) {
+	return s.rdb.SetNX(ctx, key, reservation(token), lease).Result()
+}
+
+// ReserveMany implements Store, the keys are reserved in a single pipeline.
+func (s *RedisStore) ReserveMany(ctx context.Context, keys []string, token string, lease time.Duration) ([]bool, error) {
+	reserved := make([]bool, len(keys))
+	if len(keys) == 0 {
+		return reserved, nil
+	}
+
+	cmds := make([]*redis.BoolCmd, len(keys))
+
+	_, err := s.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			cmds[i] = pipe.SetNX(ctx, key, reservation(token), lease)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i, cmd := range cmds {
+		reserved[i] = cmd.Val()
+	}
+
+	return reserved, nil
+}
+
+// Commit implements Store.
+func (s *RedisStore) Commit(ctx context.Context, key, value string, ttl time.Duration) error {
+	return s.rdb.Set(ctx, key, value, ttl).Err()
+}
+
+// Release implements Store.
+func (s *RedisStore) Release(ctx context.Context, key, token string) error {
+	return releaseScript.Run(ctx, s.rdb, []string{key}, reservation(token)).Err()
+}
+
+// Extend implements Store.
+func (s *RedisStore) Extend(ctx context.Context, key, token string, lease time.Duration) error {
+	return extendScript.Run(ctx, s.rdb, []string{key}, reservation(token), lease.Milliseconds()).Err()
+}
+
+// TTL implements Store.
+func (s *RedisStore) TTL(ctx context.Context, key string) (time.Duration, bool, error) {
+	ttl, err := s.rdb.PTTL(ctx, key).Result()
+	if err != nil {
+		return 0, false, err
+	}
+
+	// Redis replies -2 if the key doesn't exist and -1 if it has no expiration.
+	switch ttl {
+	case -2:
+		return 0, false, nil
+	case -1:
+		return 0, true, nil
+	}
+
+	return ttl, true, nil
+}
+
+// Delete implements Store.
+func (s *RedisStore) Delete(ctx context.Context, key string) error {
+	return s.rdb.Del(ctx, key).Err()
+}
+
+// Keys implements Store.
+func (s *RedisStore) Keys(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+
+	iter := s.rdb.Scan(ctx, 0, escapePattern(prefix)+"*", 0).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+
+	return keys, iter.Err()
+}
+
+// Rename implements Store.
+func (s *RedisStore) Rename(ctx context.Context, from, to string) (bool, error) {
+	return s.rdb.RenameNX(ctx, from, to).Result()
+}
+
+// escapePattern escapes the glob characters of a SCAN pattern.
+func escapePattern(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		"*", `\*`,
+		"?", `\?`,
+		"[", `\[`,
+		"]", `\]`,
+	).Replace(s)
+}
+
+// Close implements Store.
+func (s *RedisStore) Close() error {
+	if s.shared {
+		return nil
+	}
+
+	return s.rdb.Close()
+}
@@ -0,0 +1,348 @@
+package seen
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testStore runs the two phases against any Store.
+func testStore(t *testing.T, s Store) {
+	ctx := context.Background()
+	key := "seen/test"
+
+	// only one run can reserve the key.
+	reserved, err := s.Reserve(ctx, key, "first", time.Minute)
+	require.NoError(t, err)
+	require.True(t, reserved)
+
+	reserved, err = s.Reserve(ctx, key, "second", time.Minute)
+	require.NoError(t, err)
+	require.False(t, reserved)
+
+	// a run can't release the reservation of another run.
+	require.NoError(t, s.Release(ctx, key, "second"))
+	reserved, err = s.Reserve(ctx, key, "second", time.Minute)
+	require.NoError(t, err)
+	require.False(t, reserved)
+
+	// once released, the key can be reserved again.
+	require.NoError(t, s.Release(ctx, key, "first"))
+	reserved, err = s.Reserve(ctx, key, "second", time.Second)
+	require.NoError(t, err)
+	require.True(t, reserved)
+
+	// a committed key can't be released nor reserved.
+	require.NoError(t, s.Commit(ctx, key, "https://github.com/seen/test", time.Hour))
+	require.NoError(t, s.Release(ctx, key, "second"))
+	reserved, err = s.Reserve(ctx, key, "third", time.Minute)
+	require.NoError(t, err)
+	require.False(t, reserved)
+
+	value, ok, err := s.Get(ctx, key)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "https://github.com/seen/test", value)
+
+	_, ok, err = s.Get(ctx, "seen/missing")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	keys, err := s.Keys(ctx, "seen/te")
+	require.NoError(t, err)
+	require.Equal(t, []string{key}, keys)
+
+	ttl, ok, err := s.TTL(ctx, key)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.InDelta(t, time.Hour, ttl, float64(time.Minute))
+
+	// a key committed with no ttl never expires.
+	require.NoError(t, s.Commit(ctx, "seen/forever", "blocked", 0))
+	ttl, ok, err = s.TTL(ctx, "seen/forever")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Zero(t, ttl)
+
+	require.NoError(t, s.Delete(ctx, "seen/forever"))
+	_, ok, err = s.TTL(ctx, "seen/forever")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// keys are reserved and read in batches.
+	reserved2, err := s.ReserveMany(ctx, []string{"seen/batch", key, "seen/batch"}, "first", time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, []bool{true, false, false}, reserved2)
+
+	values, err := s.GetMany(ctx, []string{key, "seen/missing"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{key: "https://github.com/seen/test"}, values)
+
+	require.NoError(t, s.Release(ctx, "seen/batch", "first"))
+
+	// an expired reservation can be taken by another run.
+	other := "seen/expired"
+	reserved, err = s.Reserve(ctx, other, "first", time.Second)
+	require.NoError(t, err)
+	require.True(t, reserved)
+
+	// extending the reservation keeps it past its first lease.
+	require.NoError(t, s.Extend(ctx, other, "first", 3*time.Second))
+	time.Sleep(1100 * time.Millisecond)
+
+	reserved, err = s.Reserve(ctx, other, "second", time.Second)
+	require.NoError(t, err)
+	require.False(t, reserved)
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, NewMemoryStore())
+}
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "seen.db")
+
+	s, err := NewFileStore(path)
+	require.NoError(t, err)
+	testStore(t, s)
+
+	// the keys survive reopening the file.
+	reopened, err := NewFileStore(path)
+	require.NoError(t, err)
+
+	reserved, err := reopened.Reserve(context.Background(), "seen/test", "fourth", time.Minute)
+	require.NoError(t, err)
+	require.False(t, reserved)
+}
+
+func TestFileStoreConcurrentReserve(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "seen.db")
+
+	// every store stands for a run of its own, sharing the file.
+	var (
+		wg  sync.WaitGroup
+		won atomic.Int32
+	)
+	for i := 0; i < 8; i++ {
+		s, err := NewFileStore(path)
+		require.NoError(t, err)
+
+		wg.Add(1)
+		go func(token string) {
+			defer wg.Done()
+
+			reserved, err := s.Reserve(context.Background(), "seen/race", token, time.Minute)
+			require.NoError(t, err)
+			if reserved {
+				won.Add(1)
+			}
+		}(fmt.Sprint(i))
+	}
+	wg.Wait()
+
+	require.Equal(t, int32(1), won.Load())
+}
+
+func TestFileStoreConvertJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "seen.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"seen/old": {"value": "published", "expires_at": "0001-01-01T00:00:00Z"}}`), 0o644))
+
+	s, err := NewFileStore(path)
+	require.NoError(t, err)
+
+	value, ok, err := s.Get(context.Background(), "seen/old")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "published", value)
+
+	// the JSON file is kept aside.
+	_, err = os.Stat(path + ".bak")
+	require.NoError(t, err)
+}
+
+func TestRedisStore(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping short mode")
+	}
+
+	s, err := NewRedisStore(os.Getenv("REDIS_URI"))
+	require.NoError(t, err)
+	defer s.Close()
+
+	s.rdb.Del(context.Background(), "seen/test", "seen/expired", "seen/batch")
+	testStore(t, s)
+}
+
+func TestOpen(t *testing.T) {
+	var tests = []struct {
+		name   string
+		uri    string
+		hasErr bool
+	}{
+		{name: "redis", uri: "redis://default:@localhost:6379"},
+		{name: "memory", uri: "memory://"},
+		{name: "absolute file", uri: "file://" + filepath.Join(t.TempDir(), "seen.json")},
+		{name: "file without path", uri: "file://", hasErr: true},
+		{name: "unsupported", uri: "bolt:///tmp/seen.db", hasErr: true},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := Open(tc.uri)
+			if tc.hasErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.NoError(t, s.Close())
+		})
+	}
+}
+
+func TestNamespace(t *testing.T) {
+	n := Namespace{Sink: "nostr", Channel: "default", Language: "Go"}
+
+	key := n.Key("danvergara/dblab")
+	require.Equal(t, "seen:nostr:default:go:danvergara/dblab", key)
+
+	fullName, ok := n.FullName(key)
+	require.True(t, ok)
+	require.Equal(t, "danvergara/dblab", fullName)
+
+	rust := Namespace{Sink: "nostr", Channel: "default", Language: "Rust"}
+	_, ok = rust.FullName(key)
+	require.False(t, ok)
+}
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	n := Namespace{Sink: "nostr", Channel: "default", Language: "Go"}
+
+	require.NoError(t, s.Commit(ctx, "danvergara/dblab", "https://github.com/danvergara/dblab", time.Hour))
+	require.NoError(t, s.Commit(ctx, "go-resty/resty", "https://github.com/go-resty/resty", time.Hour))
+	// keys that are not legacy are left alone.
+	require.NoError(t, s.Commit(ctx, "events:go-resty/resty", "event-id", time.Hour))
+	require.NoError(t, s.Commit(ctx, "go-resty/resty:lock", "reserved:token", time.Hour))
+	// keys already in the namespace are not overwritten.
+	require.NoError(t, s.Commit(ctx, n.Key("go-resty/resty"), "newer", time.Hour))
+
+	moved, err := Migrate(ctx, s, n)
+	require.NoError(t, err)
+	require.Equal(t, 1, moved)
+
+	value, ok, err := s.Get(ctx, n.Key("danvergara/dblab"))
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "https://github.com/danvergara/dblab", value)
+
+	_, ok, err = s.Get(ctx, "danvergara/dblab")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	value, _, err = s.Get(ctx, n.Key("go-resty/resty"))
+	require.NoError(t, err)
+	require.Equal(t, "newer", value)
+
+	keys, err := s.Keys(ctx, n.Prefix())
+	require.NoError(t, err)
+	require.Equal(t, []string{n.Key("danvergara/dblab"), n.Key("go-resty/resty")}, keys)
+}
+
+func TestParseRecord(t *testing.T) {
+	record := Record{URL: "https://github.com/foo/bar", Stars: 10, PublishedAt: time.Now().UTC(), EventID: "event-id"}
+
+	got, ok := ParseRecord(record.String())
+	require.True(t, ok)
+	require.Equal(t, record.Stars, got.Stars)
+	require.Equal(t, record.EventID, got.EventID)
+	require.True(t, record.PublishedAt.Equal(got.PublishedAt))
+
+	_, ok = ParseRecord("https://github.com/foo/bar")
+	require.False(t, ok)
+}
+
+func TestAdmin(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	n := Namespace{Sink: "nostr", Channel: "default", Language: "Go"}
+
+	record := Record{URL: "https://github.com/foo/bar", Stars: 100, PublishedAt: time.Now(), EventID: "abc"}
+	require.NoError(t, Mark(ctx, s, n, "foo/bar", record, time.Hour))
+	require.NoError(t, Mark(ctx, s, n, "foo/blocked", Record{Blocked: true}, time.Hour))
+	require.NoError(t, s.Commit(ctx, n.Key("foo/legacy"), "https://github.com/foo/legacy", time.Hour))
+
+	reserved, err := s.Reserve(ctx, LockKey(n.Key("foo/bar")), "run", time.Minute)
+	require.NoError(t, err)
+	require.True(t, reserved)
+
+	// repos of other namespaces are not listed.
+	require.NoError(t, s.Commit(ctx, Namespace{Sink: "nostr", Channel: "other", Language: "Go"}.Key("foo/other"), "", 0))
+
+	entries, err := List(ctx, s, n)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+
+	require.Equal(t, "foo/bar", entries[0].FullName)
+	require.Equal(t, "abc", entries[0].Record.EventID)
+	require.True(t, entries[0].Reserved)
+	require.NotZero(t, entries[0].TTL)
+
+	// blocked repos never expire.
+	require.Equal(t, "foo/blocked", entries[1].FullName)
+	require.True(t, entries[1].Record.Blocked)
+	require.Zero(t, entries[1].TTL)
+
+	require.Equal(t, "foo/legacy", entries[2].FullName)
+	require.Nil(t, entries[2].Record)
+
+	var buf bytes.Buffer
+	exported, err := Export(ctx, s, n, &buf)
+	require.NoError(t, err)
+	require.Equal(t, 3, exported)
+
+	// forgetting a repo drops its reservation too.
+	require.NoError(t, Forget(ctx, s, n, "foo/bar"))
+	_, ok, err := Get(ctx, s, n, "foo/bar")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	_, ok, err = s.Get(ctx, LockKey(n.Key("foo/bar")))
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// the export can be imported in another namespace.
+	other := Namespace{Sink: "nostr", Channel: "imported", Language: "Go"}
+	imported, err := Import(ctx, s, other, &buf)
+	require.NoError(t, err)
+	require.Equal(t, 3, imported)
+
+	entry, ok, err := Get(ctx, s, other, "foo/bar")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, 100, entry.Record.Stars)
+	require.False(t, entry.Reserved)
+
+	_, err = Import(ctx, s, other, strings.NewReader("{\"value\":\"x\"}\n"))
+	require.Error(t, err)
+
+	// a repo about to expire is exported with a TTL, not as one that never expires.
+	short := Namespace{Sink: "nostr", Channel: "short", Language: "Go"}
+	require.NoError(t, s.Commit(ctx, short.Key("foo/short"), "", 500*time.Millisecond))
+
+	buf.Reset()
+	_, err = Export(ctx, s, short, &buf)
+	require.NoError(t, err)
+	require.Contains(t, buf.String(), `"ttl_seconds":1`)
+}
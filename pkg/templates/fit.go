@@ -0,0 +1,143 @@
+package templates
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// keptTags is the number of hashtags kept while there's text left to shorten,
+	// the first ones are the hashtags of the language.
+	keptTags = 2
+	// minLineLength is the length a line of text is never shortened below
+	// while there are hashtags left to drop.
+	minLineLength = 40
+	// ellipsis ends the shortened text.
+	ellipsis = "…"
+)
+
+var urlPattern = regexp.MustCompile(`https?://\S+`)
+
+// LengthFunc returns the length of a text as a destination counts it.
+type LengthFunc func(text string) int
+
+// RuneCount counts every character, including the ones of the links.
+func RuneCount(text string) int {
+	return utf8.RuneCountInString(text)
+}
+
+// Fit shortens a rendered note to limit characters as counted by length, in order:
+// it drops the hashtags of the topics, shortens the longest lines of text,
+// drops the hashtags left and, as a last resort, cuts the end of the text.
+// The links are never shortened.
+func Fit(text string, limit int, length LengthFunc) string {
+	if limit <= 0 || length(text) <= limit {
+		return text
+	}
+
+	lines := dropTags(strings.Split(text, "\n"), keptTags, limit, length)
+
+	for !fits(lines, limit, length) {
+		i := longestLine(lines)
+		if i < 0 {
+			break
+		}
+
+		excess := length(strings.Join(lines, "\n")) - limit
+		n := utf8.RuneCountInString(lines[i]) - excess
+		if n < minLineLength {
+			n = minLineLength
+		}
+
+		lines[i] = shorten(lines[i], n)
+	}
+
+	text = strings.Join(dropTags(lines, 0, limit, length), "\n")
+	if length(text) <= limit {
+		return text
+	}
+
+	runes := []rune(text)
+	for len(runes) > 0 && length(string(runes)+ellipsis) > limit {
+		runes = runes[:len(runes)-1]
+	}
+
+	return strings.TrimSpace(string(runes)) + ellipsis
+}
+
+// fits reports whether the lines fit in limit characters.
+func fits(lines []string, limit int, length LengthFunc) bool {
+	return length(strings.Join(lines, "\n")) <= limit
+}
+
+// isTagLine reports whether the line only holds hashtags.
+func isTagLine(line string) bool {
+	fields := strings.Fields(line)
+	for _, f := range fields {
+		if !strings.HasPrefix(f, "#") {
+			return false
+		}
+	}
+
+	return len(fields) != 0
+}
+
+// dropTags drops the hashtags from the end of the text until it fits in limit characters,
+// keeping the first keep hashtags of every line. The lines left without hashtags are dropped.
+func dropTags(lines []string, keep, limit int, length LengthFunc) []string {
+	for i := len(lines) - 1; i >= 0 && !fits(lines, limit, length); i-- {
+		if !isTagLine(lines[i]) {
+			continue
+		}
+
+		tags := strings.Fields(lines[i])
+		for len(tags) > keep && !fits(lines, limit, length) {
+			tags = tags[:len(tags)-1]
+			lines[i] = strings.Join(tags, " ")
+		}
+
+		if len(tags) == 0 {
+			lines = append(lines[:i], lines[i+1:]...)
+		}
+	}
+
+	return lines
+}
+
+// longestLine returns the index of the longest line that can be shortened,
+// a line without links nor hashtags only, longer than minLineLength. It returns -1 if there's none.
+func longestLine(lines []string) int {
+	longest, n := -1, minLineLength
+
+	for i, line := range lines {
+		if urlPattern.MatchString(line) || isTagLine(line) {
+			continue
+		}
+
+		if l := utf8.RuneCountInString(line); l > n {
+			longest, n = i, l
+		}
+	}
+
+	return longest
+}
+
+// shorten cuts the line to n characters, at the end of a word if there's one
+// in the second half of the line, and ends it with an ellipsis.
+func shorten(line string, n int) string {
+	runes := []rune(line)
+	if len(runes) <= n {
+		return line
+	}
+
+	cut := string(runes[:n-1])
+	if runes[n-1] != ' ' {
+		// The cut is in the middle of a word.
+		if i := strings.LastIndex(cut, " "); i > len(cut)/2 {
+			cut = cut[:i]
+		}
+	}
+
+	return strings.TrimRight(cut, " ,.;:-") + ellipsis
+}
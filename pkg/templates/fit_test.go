@@ -0,0 +1,72 @@
+package templates
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFit(t *testing.T) {
+	description := strings.Repeat("a good project ", 30)
+	status := "foo/bar: " + description + "\nAuthor: foo\n⭐: 12.3k\nhttps://github.com/foo/bar\n#golang #programming #cli #machinelearning"
+
+	tests := []struct {
+		name  string
+		text  string
+		limit int
+		check func(t *testing.T, fitted string)
+	}{
+		{
+			name:  "short enough",
+			text:  "foo/bar: a good project\nhttps://github.com/foo/bar\n#golang",
+			limit: 500,
+			check: func(t *testing.T, fitted string) {
+				require.Equal(t, "foo/bar: a good project\nhttps://github.com/foo/bar\n#golang", fitted)
+			},
+		},
+		{
+			name:  "drops the hashtags of the topics first",
+			text:  "foo/bar: a good project\nhttps://github.com/foo/bar\n#golang #programming #cli #machinelearning",
+			limit: 73,
+			check: func(t *testing.T, fitted string) {
+				require.Equal(t, "foo/bar: a good project\nhttps://github.com/foo/bar\n#golang #programming", fitted)
+			},
+		},
+		{
+			name:  "shortens the description",
+			text:  status,
+			limit: 200,
+			check: func(t *testing.T, fitted string) {
+				require.LessOrEqual(t, RuneCount(fitted), 200)
+				require.Regexp(t, ` (a|good|project)…\nAuthor: foo`, fitted, "the description is cut at the end of a word")
+				require.Contains(t, fitted, "\nhttps://github.com/foo/bar\n#golang #programming")
+			},
+		},
+		{
+			name:  "drops every hashtag",
+			text:  status,
+			limit: 90,
+			check: func(t *testing.T, fitted string) {
+				require.LessOrEqual(t, RuneCount(fitted), 90)
+				require.NotContains(t, fitted, "#")
+				require.True(t, strings.HasSuffix(fitted, "https://github.com/foo/bar"), fitted)
+			},
+		},
+		{
+			name:  "cuts the end as a last resort",
+			text:  status,
+			limit: 40,
+			check: func(t *testing.T, fitted string) {
+				require.LessOrEqual(t, RuneCount(fitted), 40)
+				require.True(t, strings.HasSuffix(fitted, "…"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.check(t, Fit(tt.text, tt.limit, RuneCount))
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package templates
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+)
+
+// defaultEmoji is shown for the languages without an emoji.
+const defaultEmoji = "💻"
+
+// now returns the current time, it's replaced by the tests.
+var now = time.Now
+
+// Funcs returns the functions available to the templates,
+// the languages give the emoji and the hashtags of every language:
+//
+//	stars 12345             "12.3k"
+//	truncate 10 "a long description"  "a long de…"
+//	hashtags .Topics        "#cli #machinelearning"
+//	tags .Language          "#golang #programming"
+//	emoji .Language         "🐹"
+//	ago .PushedAt           "3 hours ago"
+func Funcs(languages Languages) template.FuncMap {
+	return template.FuncMap{
+		"stars":    humanize,
+		"truncate": truncate,
+		"hashtags": hashtags,
+		"tags":     languages.tags,
+		"emoji":    languages.emoji,
+		"ago":      ago,
+	}
+}
+
+// humanize returns n with a k or M suffix once it reaches a thousand.
+func humanize(n int) string {
+	switch {
+	case n >= 1_000_000:
+		return trimDecimal(float64(n)/1_000_000) + "M"
+	case n >= 1_000:
+		return trimDecimal(float64(n)/1_000) + "k"
+	}
+
+	return fmt.Sprint(n)
+}
+
+// trimDecimal formats f with a single decimal, dropping it if it's zero.
+func trimDecimal(f float64) string {
+	s := fmt.Sprintf("%.1f", float64(int(f*10))/10)
+	return strings.TrimSuffix(s, ".0")
+}
+
+// truncate cuts s to n characters, ending it with an ellipsis if it was cut.
+func truncate(n int, s string) string {
+	runes := []rune(s)
+	if n <= 0 || len(runes) <= n {
+		return s
+	}
+
+	return strings.TrimSpace(string(runes[:n-1])) + "…"
+}
+
+// hashtags turns the topics into hashtags, dropping the characters hashtags can't hold.
+func hashtags(topics []string) string {
+	tags := make([]string, 0, len(topics))
+
+	for _, topic := range topics {
+		tag := strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+				return r
+			}
+			return -1
+		}, topic)
+
+		if tag != "" {
+			tags = append(tags, "#"+tag)
+		}
+	}
+
+	return strings.Join(tags, " ")
+}
+
+// ago returns the time elapsed since t in words.
+func ago(t time.Time) string {
+	d := now().Sub(t)
+
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return plural(int(d/time.Minute), "minute") + " ago"
+	case d < 24*time.Hour:
+		return plural(int(d/time.Hour), "hour") + " ago"
+	case d < 30*24*time.Hour:
+		return plural(int(d/(24*time.Hour)), "day") + " ago"
+	case d < 365*24*time.Hour:
+		return plural(int(d/(30*24*time.Hour)), "month") + " ago"
+	}
+
+	return plural(int(d/(365*24*time.Hour)), "year") + " ago"
+}
+
+func plural(n int, unit string) string {
+	if n == 1 {
+		return "1 " + unit
+	}
+
+	return fmt.Sprintf("%d %ss", n, unit)
+}
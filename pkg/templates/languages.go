@@ -0,0 +1,122 @@
+package templates
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"strings"
+)
+
+// defaultLanguage is the key of the settings used for the languages missing in the mapping.
+const defaultLanguage = "default"
+
+// Language is how the repos of a programming language are presented.
+type Language struct {
+	// Hashtags are added to the notes, without the leading #.
+	Hashtags []string `json:"hashtags"`
+	// Emoji is shown by the emoji function.
+	Emoji string `json:"emoji"`
+	// Template is the variant of the templates used for the language,
+	// e.g. "rust" renders repo.rust.tmpl instead of repo.tmpl if it exists.
+	Template string `json:"template,omitempty"`
+}
+
+// Languages maps the lowercased GitHub languages to their settings,
+// the "default" entry is used for the missing ones.
+type Languages map[string]Language
+
+// LoadLanguages reads the mapping from a JSON file,
+// the languages missing in the file keep their default settings.
+func LoadLanguages(path string) (Languages, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var overrides Languages
+	if err := json.Unmarshal(b, &overrides); err != nil {
+		return nil, err
+	}
+
+	languages := defaultLanguages()
+	for name, lang := range overrides {
+		languages[strings.ToLower(name)] = lang
+	}
+
+	return languages, nil
+}
+
+// defaultLanguages returns the embedded mapping.
+func defaultLanguages() Languages {
+	b, err := defaults.ReadFile(languagesFile)
+	if err != nil {
+		panic(err)
+	}
+
+	var languages Languages
+	// The embedded mapping is checked by the tests, it can't fail to parse.
+	if err := json.Unmarshal(b, &languages); err != nil {
+		panic(err)
+	}
+
+	return languages
+}
+
+// Get returns the settings of the language, or the default ones if it's missing.
+func (l Languages) Get(language string) Language {
+	if lang, ok := l[strings.ToLower(language)]; ok {
+		return lang
+	}
+
+	return l[defaultLanguage]
+}
+
+// Known reports whether the language is in the mapping, ignoring case.
+func (l Languages) Known(language string) bool {
+	if strings.EqualFold(language, defaultLanguage) {
+		return false
+	}
+
+	_, ok := l[strings.ToLower(language)]
+	return ok
+}
+
+// Names returns the languages of the mapping, sorted.
+func (l Languages) Names() []string {
+	names := make([]string, 0, len(l))
+	for name := range l {
+		if name != defaultLanguage {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// Hashtags returns the hashtags of the language, without the leading #.
+func (l Languages) Hashtags(language string) []string {
+	return l.Get(language).Hashtags
+}
+
+// tags returns the hashtags of the language ready to be appended to a note, e.g. "#golang #programming".
+func (l Languages) tags(language string) string {
+	hashtags := l.Hashtags(language)
+
+	tags := make([]string, len(hashtags))
+	for i, hashtag := range hashtags {
+		tags[i] = "#" + hashtag
+	}
+
+	return strings.Join(tags, " ")
+}
+
+// emoji returns the emoji of the language.
+func (l Languages) emoji(language string) string {
+	if e := l.Get(language).Emoji; e != "" {
+		return e
+	}
+
+	return defaultEmoji
+}
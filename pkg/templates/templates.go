@@ -0,0 +1,171 @@
+// Package templates renders the notes published for every repo.
+// The default templates are embedded in the binary,
+// any of them can be overridden by a file with the same name.
+// A language can use its own variant of a template, e.g. repo.rust.tmpl.
+package templates
+
+import (
+	"bytes"
+	"embed"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+const (
+	// Repo is the template of a repo published for the first time.
+	Repo = "repo.tmpl"
+	// Milestone is the template of a repo published again.
+	Milestone = "milestone.tmpl"
+	// languagesFile holds the mapping of the languages, embedded or in the template directory.
+	languagesFile = "languages.json"
+)
+
+//go:embed *.tmpl languages.json
+var defaults embed.FS
+
+// Options tells where to find the templates overriding the embedded ones.
+type Options struct {
+	// Dir holds template files named after the embedded ones, e.g. repo.tmpl,
+	// the variants of the languages, e.g. repo.rust.tmpl, and languages.json.
+	// The missing ones keep their default.
+	Dir string
+	// Repo is a file overriding the repo template, it takes precedence over Dir.
+	Repo string
+	// Languages is a JSON file mapping the languages to their settings,
+	// it takes precedence over the languages.json of Dir.
+	Languages string
+	// Sink is the destination the notes are rendered for, e.g. "nostr".
+	// The templates of the sink subdirectory of Dir, e.g. nostr/repo.tmpl, take precedence over the ones of Dir.
+	Sink string
+}
+
+// Set holds the parsed templates, it's parsed once and executed for every repo.
+type Set struct {
+	tmpl      *template.Template
+	languages Languages
+}
+
+// Default returns the embedded templates.
+func Default() *Set {
+	languages := defaultLanguages()
+
+	// The embedded templates are checked by the tests, they can't fail to parse.
+	return &Set{tmpl: template.Must(parseDefaults(languages)), languages: languages}
+}
+
+// Load returns the embedded templates overridden by the files given in opts.
+func Load(opts Options) (*Set, error) {
+	languages := defaultLanguages()
+
+	languagesPath := opts.Languages
+	if languagesPath == "" && opts.Dir != "" {
+		path := filepath.Join(opts.Dir, languagesFile)
+		if _, err := os.Stat(path); err == nil {
+			languagesPath = path
+		}
+	}
+
+	if languagesPath != "" {
+		var err error
+		if languages, err = LoadLanguages(languagesPath); err != nil {
+			return nil, err
+		}
+	}
+
+	tmpl, err := parseDefaults(languages)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Dir != "" {
+		paths, err := filepath.Glob(filepath.Join(opts.Dir, "*.tmpl"))
+		if err != nil {
+			return nil, err
+		}
+
+		if opts.Sink != "" {
+			sinkPaths, err := filepath.Glob(filepath.Join(opts.Dir, opts.Sink, "*.tmpl"))
+			if err != nil {
+				return nil, err
+			}
+
+			paths = append(paths, sinkPaths...)
+		}
+
+		for _, path := range paths {
+			if err := parseFile(tmpl, filepath.Base(path), path); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if opts.Repo != "" {
+		if err := parseFile(tmpl, Repo, opts.Repo); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Set{tmpl: tmpl, languages: languages}, nil
+}
+
+// DefaultDir returns the config directory searched for templates,
+// e.g. ~/.config/github-inspector/templates, or an empty string if there's none.
+func DefaultDir() string {
+	config, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+
+	dir := filepath.Join(config, "github-inspector", "templates")
+	if _, err := os.Stat(dir); err != nil {
+		return ""
+	}
+
+	return dir
+}
+
+// Languages returns the mapping of the languages.
+func (s *Set) Languages() Languages {
+	return s.languages
+}
+
+// Execute renders the named template with data, using the variant of the language if there's one.
+func (s *Set) Execute(name, language string, data interface{}) (string, error) {
+	buf := &bytes.Buffer{}
+
+	if v := s.languages.Get(language).Template; v != "" {
+		if variant := variantName(name, v); s.tmpl.Lookup(variant) != nil {
+			name = variant
+		}
+	}
+
+	if err := s.tmpl.ExecuteTemplate(buf, name, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// variantName returns the name of the variant of a template, e.g. repo.rust.tmpl.
+func variantName(name, variant string) string {
+	return strings.TrimSuffix(name, ".tmpl") + "." + variant + ".tmpl"
+}
+
+// parseDefaults parses the embedded templates along with the function library.
+func parseDefaults(languages Languages) (*template.Template, error) {
+	return template.New("").Funcs(Funcs(languages)).ParseFS(defaults, "*.tmpl")
+}
+
+// parseFile replaces the named template with the content of the file at path.
+func parseFile(tmpl *template.Template, name, path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	_, err = tmpl.New(name).Parse(string(b))
+
+	return err
+}
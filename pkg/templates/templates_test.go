@@ -0,0 +1,178 @@
+package templates
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type repo struct {
+	FullName        string
+	Language        string
+	Description     string
+	Comment         string
+	HtmlURL         string
+	StargazersCount int
+	Topics          []string
+	Owner           struct{ Login string }
+}
+
+func TestDefault(t *testing.T) {
+	r := repo{
+		FullName:        "foo/bar",
+		Language:        "Go",
+		Description:     "a good project",
+		HtmlURL:         "https://github.com/foo/bar",
+		StargazersCount: 12345,
+		Topics:          []string{"cli", "machine-learning"},
+	}
+	r.Owner.Login = "foo"
+
+	content, err := Default().Execute(Repo, r.Language, r)
+	require.NoError(t, err)
+	require.Equal(t, "foo/bar: a good project\nAuthor: foo\n⭐: 12.3k\nhttps://github.com/foo/bar\n#golang #programming #cli #machinelearning", content)
+
+	r.Comment = "our pick of the day"
+	content, err = Default().Execute(Repo, r.Language, r)
+	require.NoError(t, err)
+	require.Equal(t, "foo/bar: a good project\n💬 our pick of the day\nAuthor: foo\n⭐: 12.3k\nhttps://github.com/foo/bar\n#golang #programming #cli #machinelearning", content)
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, Milestone), []byte("milestone {{.FullName}}"), 0o644))
+
+	repoFile := filepath.Join(t.TempDir(), "custom.tmpl")
+	require.NoError(t, os.WriteFile(repoFile, []byte("{{emoji .Language}} {{.FullName}}"), 0o644))
+
+	// the directory overrides the milestone template, the flag the repo one.
+	s, err := Load(Options{Dir: dir, Repo: repoFile})
+	require.NoError(t, err)
+
+	content, err := s.Execute(Milestone, "Go", struct{ FullName string }{"foo/bar"})
+	require.NoError(t, err)
+	require.Equal(t, "milestone foo/bar", content)
+
+	content, err = s.Execute(Repo, "Go", struct{ FullName, Language string }{"foo/bar", "Go"})
+	require.NoError(t, err)
+	require.Equal(t, "🐹 foo/bar", content)
+
+	// templates missing in the directory keep their default.
+	s, err = Load(Options{Dir: dir})
+	require.NoError(t, err)
+
+	content, err = s.Execute(Repo, "Go", repo{FullName: "foo/bar", Language: "Go"})
+	require.NoError(t, err)
+	require.Contains(t, content, "#golang")
+
+	// the templates of a sink take precedence over the ones of the directory.
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "mastodon"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "mastodon", Milestone), []byte("toot {{.FullName}}"), 0o644))
+
+	s, err = Load(Options{Dir: dir, Sink: "mastodon"})
+	require.NoError(t, err)
+
+	content, err = s.Execute(Milestone, "Go", struct{ FullName string }{"foo/bar"})
+	require.NoError(t, err)
+	require.Equal(t, "toot foo/bar", content)
+
+	s, err = Load(Options{Dir: dir, Sink: "nostr"})
+	require.NoError(t, err)
+
+	content, err = s.Execute(Milestone, "Go", struct{ FullName string }{"foo/bar"})
+	require.NoError(t, err)
+	require.Equal(t, "milestone foo/bar", content)
+
+	require.NoError(t, os.WriteFile(repoFile, []byte("{{.FullName"), 0o644))
+	_, err = Load(Options{Repo: repoFile})
+	require.Error(t, err)
+}
+
+func TestLanguages(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "languages.json"), []byte(`{
+		"Rust": {"hashtags": ["rust", "rustlang"], "emoji": "🦀", "template": "rust"},
+		"default": {"hashtags": ["opensource"]}
+	}`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "repo.rust.tmpl"), []byte("{{emoji .Language}} {{.FullName}} {{tags .Language}}"), 0o644))
+
+	s, err := Load(Options{Dir: dir})
+	require.NoError(t, err)
+
+	var tests = []struct {
+		name string
+		repo repo
+		want string
+	}{
+		{
+			name: "language with a variant",
+			repo: repo{FullName: "foo/bar", Language: "Rust"},
+			want: "🦀 foo/bar #rust #rustlang",
+		},
+		{
+			name: "language missing in the file keeps its default",
+			repo: repo{FullName: "foo/bar", Language: "Go"},
+			want: "foo/bar: \nAuthor: \n⭐: 0\n\n#golang #programming",
+		},
+		{
+			name: "unknown language",
+			repo: repo{FullName: "foo/bar", Language: "COBOL"},
+			want: "foo/bar: \nAuthor: \n⭐: 0\n\n#opensource",
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			content, err := s.Execute(Repo, tc.repo.Language, tc.repo)
+			require.NoError(t, err)
+			require.Equal(t, tc.want, content)
+		})
+	}
+
+	// the embedded mapping keeps the hashtags the bot always used for Go.
+	require.Equal(t, []string{"golang", "programming"}, Default().Languages().Hashtags("go"))
+
+	require.True(t, s.Languages().Known("rust"))
+	require.True(t, s.Languages().Known("Go"))
+	require.False(t, s.Languages().Known("COBOL"))
+	require.False(t, s.Languages().Known("default"))
+	require.NotContains(t, s.Languages().Names(), "default")
+}
+
+func TestFuncs(t *testing.T) {
+	now = func() time.Time { return time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC) }
+	defer func() { now = time.Now }()
+
+	var tests = []struct {
+		name string
+		got  string
+		want string
+	}{
+		{name: "stars under a thousand", got: humanize(999), want: "999"},
+		{name: "stars in thousands", got: humanize(1000), want: "1k"},
+		{name: "stars with a decimal", got: humanize(15370), want: "15.3k"},
+		{name: "stars in millions", got: humanize(1_250_000), want: "1.2M"},
+		{name: "short text", got: truncate(20, "a good project"), want: "a good project"},
+		{name: "long text", got: truncate(10, "a very good project"), want: "a very go…"},
+		{name: "hashtags", got: hashtags([]string{"cli", "machine-learning", "c++", "---"}), want: "#cli #machinelearning #c"},
+		{name: "known language", got: defaultLanguages().emoji("Rust"), want: "🦀"},
+		{name: "unknown language", got: Languages{}.emoji("COBOL"), want: defaultEmoji},
+		{name: "seconds ago", got: ago(now().Add(-10 * time.Second)), want: "just now"},
+		{name: "minute ago", got: ago(now().Add(-time.Minute)), want: "1 minute ago"},
+		{name: "hours ago", got: ago(now().Add(-5 * time.Hour)), want: "5 hours ago"},
+		{name: "days ago", got: ago(now().Add(-72 * time.Hour)), want: "3 days ago"},
+		{name: "months ago", got: ago(now().Add(-65 * 24 * time.Hour)), want: "2 months ago"},
+		{name: "years ago", got: ago(now().Add(-800 * 24 * time.Hour)), want: "2 years ago"},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, tc.got)
+		})
+	}
+}